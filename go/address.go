@@ -0,0 +1,120 @@
+package evm
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// AddressLength is the length of an Address in bytes.
+const AddressLength = 20
+
+// An Address is a 20-byte account address.
+type Address [AddressLength]byte
+
+// ErrInvalidAddress is returned by ParseAddress for malformed input.
+var ErrInvalidAddress = errors.New("invalid address")
+
+// ParseAddress parses a hex address with an optional 0x prefix. Shorter
+// inputs such as "0x1337" are zero-extended on the left, as the EVM does when
+// it reads an address from a stack word, but at least one digit is required.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if s == "" {
+		return a, ErrInvalidAddress
+	}
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	if len(s) > 2*AddressLength {
+		return a, ErrInvalidAddress
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return a, ErrInvalidAddress
+	}
+	copy(a[AddressLength-len(b):], b)
+	return a, nil
+}
+
+// MustParseAddress is like ParseAddress but panics if s is malformed. It is
+// meant for addresses written as constants.
+func MustParseAddress(s string) Address {
+	a, err := ParseAddress(s)
+	if err != nil {
+		panic("evm: " + err.Error() + ": " + s)
+	}
+	return a
+}
+
+// Word returns a as a stack word.
+func (a Address) Word() Word {
+	var w Word
+	w.SetBytes(a[:])
+	return w
+}
+
+// Hex returns a as 40 lowercase hex digits with a 0x prefix.
+func (a Address) Hex() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+func (a Address) String() string {
+	return a.Hex()
+}
+
+// UnmarshalText parses text with ParseAddress, so that addresses can be read
+// from JSON strings and map keys.
+func (a *Address) UnmarshalText(text []byte) error {
+	parsed, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// CreateAddress returns the address of the contract that sender creates with
+// CREATE when its nonce is nonce: the last 20 bytes of the Keccak-256 hash of
+// the RLP encoding of the list [sender, nonce].
+func CreateAddress(sender Address, nonce uint64) Address {
+	// The list is always shorter than 56 bytes, so both it and its items
+	// take a single-byte RLP prefix.
+	enc := make([]byte, 0, 2+AddressLength+9)
+	enc = append(enc, 0, 0x80+AddressLength)
+	enc = append(enc, sender[:]...)
+	switch {
+	case nonce == 0:
+		enc = append(enc, 0x80)
+	case nonce < 0x80:
+		enc = append(enc, byte(nonce))
+	default:
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], nonce)
+		n := 8
+		for b[8-n] == 0 {
+			n--
+		}
+		enc = append(enc, 0x80+byte(n))
+		enc = append(enc, b[8-n:]...)
+	}
+	enc[0] = 0xc0 + byte(len(enc)-1)
+	return addressFromHash(Keccak256(enc))
+}
+
+// CreateAddress2 returns the address of the contract that sender creates with
+// CREATE2 from init code with hash initCodeHash (EIP-1014).
+func CreateAddress2(sender Address, salt, initCodeHash [32]byte) Address {
+	return addressFromHash(Keccak256([]byte{0xff}, sender[:], salt[:], initCodeHash[:]))
+}
+
+// addressFromHash returns the last 20 bytes of hash.
+func addressFromHash(hash [32]byte) Address {
+	var a Address
+	copy(a[:], hash[32-AddressLength:])
+	return a
+}
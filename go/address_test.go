@@ -0,0 +1,80 @@
+package evm
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{"0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d", "0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d", false},
+		{"1E79B045DC29EAE9FDC69673C9DCD7C53E5E159D", "0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d", false},
+		{"0x1337", "0x0000000000000000000000000000000000001337", false},
+		{"0x777", "0x0000000000000000000000000000000000000777", false},
+		{"0X1337", "0x0000000000000000000000000000000000001337", false},
+		{"0x0", "0x0000000000000000000000000000000000000000", false},
+		{"", "", true},
+		{"0x", "", true},
+		{"0X", "", true},
+		{"0x0Xab", "", true},
+		{"0x0xab", "", true},
+		{"0x001e79b045dc29eae9fdc69673c9dcd7c53e5e159d", "", true},
+		{"0xzz", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAddress(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseAddress(%q) error = %v; want error %t", tt.in, err, tt.err)
+			continue
+		}
+		if !tt.err && got.Hex() != tt.want {
+			t.Errorf("ParseAddress(%q) = %s; want %s", tt.in, got.Hex(), tt.want)
+		}
+	}
+}
+
+func TestCreateAddress(t *testing.T) {
+	sender := MustParseAddress("0x970e8128ab834e8eac17ab8e3812f010678cf791")
+	for _, tt := range []struct {
+		nonce uint64
+		want  string
+	}{
+		{0, "0x333c3310824b7c685133f2bedb2ca4b8b4df633d"},
+		{1, "0x8bda78331c916a08481428e4b07c96d3e916d165"},
+		{2, "0xc9ddedf451bc62ce88bf9292afb13df35b670699"},
+	} {
+		if got := CreateAddress(sender, tt.nonce).Hex(); got != tt.want {
+			t.Errorf("CreateAddress(%s, %d) = %s; want %s", sender, tt.nonce, got, tt.want)
+		}
+	}
+}
+
+// TestCreateAddress2 checks the examples of EIP-1014.
+func TestCreateAddress2(t *testing.T) {
+	for _, tt := range []struct {
+		sender, salt, initCode, want string
+	}{
+		{"0x0000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "00", "0x4d1a2e2bb4f88f0250f26ffff098b0b30b26bf38"},
+		{"0xdeadbeef00000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "00", "0xb928f69bb1d91cd65274e3c79d8986362984fda3"},
+		{"0xdeadbeef00000000000000000000000000000000", "000000000000000000000000feed000000000000000000000000000000000000", "00", "0xd04116cdd17bebe565eb2422f2497e06cc1c9833"},
+		{"0x0000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "deadbeef", "0x70f2b2914a2a4b783faefb75f459a580616fcb5e"},
+		{"0x00000000000000000000000000000000deadbeef", "00000000000000000000000000000000000000000000000000000000cafebabe", "deadbeef", "0x60f3f640a8508fc6a86d45df051962668e1e8ac7"},
+	} {
+		var salt [32]byte
+		if _, err := hex.Decode(salt[:], []byte(tt.salt)); err != nil {
+			t.Fatal(err)
+		}
+		initCode, err := hex.DecodeString(tt.initCode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := CreateAddress2(MustParseAddress(tt.sender), salt, Keccak256(initCode))
+		if got.Hex() != tt.want {
+			t.Errorf("CreateAddress2(%s, 0x%s, %s) = %s; want %s", tt.sender, tt.salt, tt.initCode, got.Hex(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package evm
+
+import "sync"
+
+// A bitvec holds one bit per byte of code.
+type bitvec []byte
+
+func (b bitvec) set(pos uint64) {
+	b[pos/8] |= 1 << (pos % 8)
+}
+
+func (b bitvec) isSet(pos uint64) bool {
+	return b[pos/8]&(1<<(pos%8)) != 0
+}
+
+// jumpdestBitmap returns the valid jump destinations in code: the offsets of
+// JUMPDEST instructions that are not part of a PUSH immediate.
+func jumpdestBitmap(code []byte) bitvec {
+	bits := make(bitvec, len(code)/8+1)
+	for pc := 0; pc < len(code); pc++ {
+		switch op := code[pc]; {
+		case op == 0x5b:
+			bits.set(uint64(pc))
+		case 0x60 <= op && op <= 0x7f:
+			pc += int(op) - 0x5f
+		}
+	}
+	return bits
+}
+
+// jumpdestCacheSize is the number of analyses a jumpdestCache holds before it
+// is emptied.
+const jumpdestCacheSize = 1024
+
+// A jumpdestCache memoizes jumpdestBitmap so code that is executed repeatedly
+// is only analyzed once. It is keyed by the code itself: Go's map hash is far
+// cheaper than analyzing the code, while Keccak-256 would cost more. It is
+// safe for concurrent use.
+type jumpdestCache struct {
+	mu   sync.Mutex
+	maps map[string]bitvec
+}
+
+// jumpdests is the cache shared by every Interpreter, so that analyses
+// outlive the interpreters the Evm wrapper creates for each call.
+var jumpdests jumpdestCache
+
+// get returns the jump destinations of code, analyzing it on first use.
+func (c *jumpdestCache) get(code []byte) bitvec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if bits, ok := c.maps[string(code)]; ok {
+		return bits
+	}
+	if c.maps == nil || len(c.maps) >= jumpdestCacheSize {
+		c.maps = make(map[string]bitvec)
+	}
+	bits := jumpdestBitmap(code)
+	c.maps[string(code)] = bits
+	return bits
+}
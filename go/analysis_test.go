@@ -0,0 +1,48 @@
+package evm
+
+import "testing"
+
+func TestJumpdestBitmap(t *testing.T) {
+	// JUMPDEST, PUSH2 0x5b5b, JUMPDEST
+	bits := jumpdestBitmap([]byte{0x5b, 0x61, 0x5b, 0x5b, 0x5b})
+	for pc, want := range []bool{true, false, false, false, true} {
+		if got := bits.isSet(uint64(pc)); got != want {
+			t.Errorf("isSet(%d) = %v; want %v", pc, got, want)
+		}
+	}
+}
+
+func TestJumpdestCache(t *testing.T) {
+	var c jumpdestCache
+	for i := 0; i < jumpdestCacheSize+1; i++ {
+		c.get([]byte{0x60, byte(i), byte(i >> 8)})
+	}
+	if n := len(c.maps); n > jumpdestCacheSize {
+		t.Errorf("cache holds %d analyses; want at most %d", n, jumpdestCacheSize)
+	}
+}
+
+func TestJumpdestCacheHit(t *testing.T) {
+	// PUSH1 4, JUMP, INVALID, JUMPDEST, PUSH1 1
+	code := []byte{0x60, 0x04, 0x56, 0xfe, 0x5b, 0x60, 0x01}
+	if _, ok := Evm(code); !ok {
+		t.Fatal("first run failed")
+	}
+	// Replace the analysis with one that has no jump destinations, which
+	// only a run that reads it from the cache can see.
+	jumpdests.mu.Lock()
+	if _, ok := jumpdests.maps[string(code)]; !ok {
+		t.Error("analysis was not cached")
+	}
+	jumpdests.maps[string(code)] = make(bitvec, 1)
+	jumpdests.mu.Unlock()
+	defer func() {
+		jumpdests.mu.Lock()
+		delete(jumpdests.maps, string(code))
+		jumpdests.mu.Unlock()
+	}()
+
+	if _, ok := Evm(code); ok {
+		t.Error("second run did not use the cached analysis")
+	}
+}
@@ -0,0 +1,202 @@
+package evm
+
+// CallCreateDepth is the maximum depth of nested calls and creates.
+const CallCreateDepth = 1024
+
+// execute runs f to completion. If it fails, the state is reverted to
+// snapshot and, unless it reverted, its remaining gas and output are
+// discarded.
+func (in *Interpreter) execute(f *Frame, snapshot int) error {
+	err := in.run(f)
+	if err != nil {
+		in.state.RevertToSnapshot(snapshot)
+		if err != ErrExecutionReverted {
+			f.Gas = 0
+			f.ReturnData = nil
+		}
+	}
+	return err
+}
+
+// child returns a frame, one level deeper than f, that runs code for call.
+func (f *Frame) child(call CallContext, code []byte, gas uint64) *Frame {
+	return &Frame{
+		CallContext: call,
+		Code:        code,
+		Gas:         gas,
+		Stack:       newStack(),
+		Memory:      newMemory(),
+		in:          f.in,
+		tx:          f.tx,
+		block:       f.block,
+		depth:       f.depth + 1,
+		readOnly:    f.readOnly,
+	}
+}
+
+// codeChild returns a child frame that runs the code of codeAddr for call.
+func (f *Frame) codeChild(call CallContext, codeAddr Address, gas uint64) *Frame {
+	return f.child(call, f.in.state.GetCode(codeAddr), gas)
+}
+
+// runChild runs the code of codeAddr for call in a child frame and returns
+// its output and remaining gas. Accounts without code succeed immediately.
+func (f *Frame) runChild(snapshot int, call CallContext, codeAddr Address, gas uint64) (ret []byte, leftOverGas uint64, err error) {
+	child := f.codeChild(call, codeAddr, gas)
+	if len(child.Code) == 0 {
+		return nil, gas, nil
+	}
+	err = f.in.execute(child, snapshot)
+	return child.ReturnData, child.Gas, err
+}
+
+// canTransfer reports whether f's account can send amount wei.
+func (f *Frame) canTransfer(amount *Word) bool {
+	if f.in.noBalanceCheck {
+		return true
+	}
+	balance := f.in.state.GetBalance(f.Address)
+	return !balance.Lt(amount)
+}
+
+// transfer moves amount wei from f's account to recipient. Only with
+// WithoutBalanceCheck can the sender hold less than amount, in which case
+// its balance is left as is.
+func (f *Frame) transfer(recipient Address, amount *Word) {
+	if amount.IsZero() {
+		return
+	}
+	db := f.in.state
+	if balance := db.GetBalance(f.Address); !balance.Lt(amount) {
+		db.SetBalance(f.Address, balance.Sub(&balance, amount))
+	}
+	balance := db.GetBalance(recipient)
+	db.SetBalance(recipient, balance.Add(&balance, amount))
+}
+
+// call sends a message from f's account to addr, transferring value and
+// running addr's code with input.
+func (f *Frame) call(addr Address, input []byte, gas uint64, value *Word) (ret []byte, leftOverGas uint64, err error) {
+	if f.depth >= CallCreateDepth {
+		return nil, gas, ErrDepth
+	}
+	if !f.canTransfer(value) {
+		return nil, gas, ErrInsufficientBalance
+	}
+	db := f.in.state
+	snapshot := db.Snapshot()
+	if !db.Exist(addr) {
+		// From SpuriousDragon, calls without value do not create empty
+		// accounts (EIP-161).
+		if f.in.rules.IsEIP158 && value.IsZero() {
+			return nil, gas, nil
+		}
+		db.CreateAccount(addr)
+	}
+	f.transfer(addr, value)
+	call := CallContext{Address: addr, Caller: f.Address, Value: *value, Input: input}
+	return f.runChild(snapshot, call, addr, gas)
+}
+
+// callCode runs addr's code in the context of f's account, which sends value
+// to itself.
+func (f *Frame) callCode(addr Address, input []byte, gas uint64, value *Word) (ret []byte, leftOverGas uint64, err error) {
+	if f.depth >= CallCreateDepth {
+		return nil, gas, ErrDepth
+	}
+	if !f.canTransfer(value) {
+		return nil, gas, ErrInsufficientBalance
+	}
+	db := f.in.state
+	call := CallContext{Address: f.Address, Caller: f.Address, Value: *value, Input: input}
+	return f.runChild(db.Snapshot(), call, addr, gas)
+}
+
+// delegateCall runs addr's code in the context of f, keeping f's caller and
+// value.
+func (f *Frame) delegateCall(addr Address, input []byte, gas uint64) (ret []byte, leftOverGas uint64, err error) {
+	if f.depth >= CallCreateDepth {
+		return nil, gas, ErrDepth
+	}
+	call := CallContext{Address: f.Address, Caller: f.Caller, Value: f.Value, Input: input}
+	return f.runChild(f.in.state.Snapshot(), call, addr, gas)
+}
+
+// staticCall is like call without value, but fails any attempt of addr's code,
+// or code it calls in turn, to modify the state.
+func (f *Frame) staticCall(addr Address, input []byte, gas uint64) (ret []byte, leftOverGas uint64, err error) {
+	if f.depth >= CallCreateDepth {
+		return nil, gas, ErrDepth
+	}
+	call := CallContext{Address: addr, Caller: f.Address, Input: input}
+	child := f.codeChild(call, addr, gas)
+	child.readOnly = true
+	if len(child.Code) == 0 {
+		return nil, gas, nil
+	}
+	err = f.in.execute(child, f.in.state.Snapshot())
+	return child.ReturnData, child.Gas, err
+}
+
+// create deploys a contract at addr: it runs code as init code in a child
+// frame and stores what that returns as the contract's code.
+func (f *Frame) create(code []byte, gas uint64, value *Word, addr Address) (ret []byte, leftOverGas uint64, err error) {
+	if f.depth >= CallCreateDepth {
+		return nil, gas, ErrDepth
+	}
+	if !f.canTransfer(value) {
+		return nil, gas, ErrInsufficientBalance
+	}
+	db, rules := f.in.state, f.in.rules
+	nonce := db.GetNonce(f.Address)
+	if nonce+1 < nonce {
+		return nil, gas, ErrNonceUintOverflow
+	}
+	db.SetNonce(f.Address, nonce+1)
+	if rules.IsBerlin {
+		db.AddAddressToAccessList(addr)
+	}
+	if db.GetNonce(addr) != 0 || len(db.GetCode(addr)) != 0 {
+		return nil, 0, ErrContractAddressCollision
+	}
+
+	snapshot := db.Snapshot()
+	db.CreateAccount(addr)
+	if rules.IsEIP158 {
+		db.SetNonce(addr, 1)
+	}
+	f.transfer(addr, value)
+
+	child := f.child(CallContext{Address: addr, Caller: f.Address, Value: *value}, code, gas)
+	err = f.in.run(child)
+	ret = child.ReturnData
+	if err == nil {
+		err = child.deposit(ret)
+	}
+	// Before Homestead, failing to pay for the code leaves an account
+	// without code rather than failing the create.
+	if err != nil && (rules.IsHomestead || err != ErrCodeStoreOutOfGas) {
+		db.RevertToSnapshot(snapshot)
+		if err != ErrExecutionReverted {
+			child.Gas = 0
+		}
+	}
+	return ret, child.Gas, err
+}
+
+// deposit stores code returned by init code as the code of f's account,
+// paying CreateDataGas per byte out of f's gas.
+func (f *Frame) deposit(code []byte) error {
+	rules := f.in.rules
+	if rules.IsEIP158 && len(code) > MaxCodeSize {
+		return ErrMaxCodeSizeExceeded
+	}
+	if rules.IsLondon && len(code) > 0 && code[0] == 0xef {
+		return ErrInvalidCode
+	}
+	if err := f.useGas(uint64(len(code)) * CreateDataGas); err != nil {
+		return ErrCodeStoreOutOfGas
+	}
+	f.in.state.SetCode(f.Address, code)
+	return nil
+}
@@ -0,0 +1,140 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidOpcode is returned when execution reaches the designated
+	// INVALID instruction (0xfe) or any other undefined opcode.
+	ErrInvalidOpcode = errors.New("invalid opcode")
+	// ErrInvalidJump is returned when JUMP or JUMPI targets anything other
+	// than a JUMPDEST instruction.
+	ErrInvalidJump = errors.New("invalid jump destination")
+	// ErrWriteProtection is returned when a static call attempts to modify
+	// state.
+	ErrWriteProtection = errors.New("write protection")
+	// ErrReturnDataOutOfBounds is returned when RETURNDATACOPY reads past the
+	// end of the last call's return data.
+	ErrReturnDataOutOfBounds = errors.New("return data out of bounds")
+	// ErrExecutionReverted is returned when execution ends with REVERT.
+	ErrExecutionReverted = errors.New("execution reverted")
+	// ErrInsufficientBalance is returned when a call or create would
+	// transfer more wei than the sender holds.
+	ErrInsufficientBalance = errors.New("insufficient balance for transfer")
+	// ErrNonceUintOverflow is returned when creating a contract would
+	// overflow the creator's nonce.
+	ErrNonceUintOverflow = errors.New("nonce uint64 overflow")
+	// ErrContractAddressCollision is returned when a contract would be
+	// created at the address of an account with code or a nonce.
+	ErrContractAddressCollision = errors.New("contract address collision")
+	// ErrCodeStoreOutOfGas is returned when init code returns more code than
+	// its remaining gas pays for.
+	ErrCodeStoreOutOfGas = errors.New("contract creation code storage out of gas")
+	// ErrMaxCodeSizeExceeded is returned when init code returns more than
+	// MaxCodeSize bytes of code (EIP-170).
+	ErrMaxCodeSizeExceeded = errors.New("max code size exceeded")
+	// ErrMaxInitCodeSizeExceeded is returned when CREATE or CREATE2 is given
+	// more than MaxInitCodeSize bytes of init code (EIP-3860).
+	ErrMaxInitCodeSizeExceeded = errors.New("max initcode size exceeded")
+	// ErrInvalidCode is returned when init code returns code starting with
+	// 0xEF (EIP-3541).
+	ErrInvalidCode = errors.New("invalid code: must not begin with 0xef")
+	// ErrDepth is returned when a call or create would exceed the call depth
+	// limit.
+	ErrDepth = errors.New("max call depth exceeded")
+	// ErrUnsupportedFork is returned when execution is requested under a
+	// Fork that is not Supported.
+	ErrUnsupportedFork = errors.New("unsupported fork")
+)
+
+// A HaltReason tells why execution stopped.
+type HaltReason int
+
+const (
+	HaltSuccess HaltReason = iota // STOP, RETURN or the end of the code
+	HaltInvalidOpcode
+	HaltStackUnderflow
+	HaltStackOverflow
+	HaltBadJumpDestination
+	HaltOutOfGas
+	HaltWriteProtection
+	HaltReturnDataOutOfBounds
+	HaltRevert
+	// HaltDepthLimit is reported to a CallTracer for a call or create beyond
+	// CallCreateDepth. The calling frame gets 0 and goes on, so no Result
+	// halts with it.
+	HaltDepthLimit
+	HaltInitCodeSize // CREATE or CREATE2 given too much init code (EIP-3860)
+	HaltUnknown      // an error outside the taxonomy
+)
+
+var haltReasonNames = [...]string{
+	HaltSuccess:               "success",
+	HaltInvalidOpcode:         "invalid opcode",
+	HaltStackUnderflow:        "stack underflow",
+	HaltStackOverflow:         "stack overflow",
+	HaltBadJumpDestination:    "bad jump destination",
+	HaltOutOfGas:              "out of gas",
+	HaltWriteProtection:       "write protection",
+	HaltReturnDataOutOfBounds: "return data out of bounds",
+	HaltRevert:                "revert",
+	HaltDepthLimit:            "depth limit",
+	HaltInitCodeSize:          "init code size exceeded",
+	HaltUnknown:               "unknown",
+}
+
+func (r HaltReason) String() string {
+	if r >= 0 && int(r) < len(haltReasonNames) {
+		return haltReasonNames[r]
+	}
+	return fmt.Sprintf("HaltReason(%d)", int(r))
+}
+
+// haltReason classifies err, which may be nil.
+func haltReason(err error) HaltReason {
+	switch {
+	case err == nil:
+		return HaltSuccess
+	case errors.Is(err, ErrInvalidOpcode):
+		return HaltInvalidOpcode
+	case errors.Is(err, ErrStackUnderflow):
+		return HaltStackUnderflow
+	case errors.Is(err, ErrStackOverflow):
+		return HaltStackOverflow
+	case errors.Is(err, ErrInvalidJump):
+		return HaltBadJumpDestination
+	case errors.Is(err, ErrOutOfGas), errors.Is(err, ErrGasUintOverflow),
+		errors.Is(err, ErrCodeStoreOutOfGas):
+		return HaltOutOfGas
+	case errors.Is(err, ErrWriteProtection):
+		return HaltWriteProtection
+	case errors.Is(err, ErrReturnDataOutOfBounds):
+		return HaltReturnDataOutOfBounds
+	case errors.Is(err, ErrExecutionReverted):
+		return HaltRevert
+	case errors.Is(err, ErrDepth):
+		return HaltDepthLimit
+	case errors.Is(err, ErrMaxInitCodeSizeExceeded):
+		return HaltInitCodeSize
+	}
+	return HaltUnknown
+}
+
+// An ExecutionError records why and where execution halted exceptionally.
+// Err is one of the sentinel errors above, so callers can also use errors.Is.
+type ExecutionError struct {
+	Reason HaltReason
+	PC     uint64 // offset of the failing instruction
+	Op     byte   // the failing instruction
+	Err    error
+}
+
+func (e *ExecutionError) Error() string {
+	return fmt.Sprintf("%v at pc %d (opcode %#02x)", e.Err, e.PC, e.Op)
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
@@ -6,991 +6,864 @@
 //
 // - Install Golang: https://golang.org/doc/install
 // - Go to the `go` directory: `cd go`
-// - Edit `evm.go` (this file!), see TODO below
 // - Run `go test ./...` to run the tests
-
-// fmt.Printf("========================\n")
-// fmt.Printf("|| %d  ||  %d  ||  %d  ||\n", var1, var2, c)
-// fmt.Printf("========================")
-
 package evm
 
 import (
-	"fmt"
 	"math"
 	"math/big"
-	"strings"
-	"unicode/utf8"
 )
 
-type FunctionMap map[byte]func(code []byte, stack []*big.Int) []*big.Int
-type BytesMap map[byte]int
-type uint256 big.Int
+// setBool sets z to 1 if b is true and 0 otherwise.
+func setBool(z *Word, b bool) {
+	if b {
+		z.SetUint64(1)
+	} else {
+		z.Clear()
+	}
+}
 
-var funcs FunctionMap
-var bytes BytesMap
+func Pop(f *Frame) error {
+	f.Stack.pop()
+	return nil
+}
 
-// func Push(code []byte, stack []*big.Int) []*big.Int {
-// 	fmt.Printf("In Push | %d ", code)
-// 	n := new(big.Int)
-// 	n.SetBytes([]byte{code})
-// 	return append(stack, n)
-// }
+func Stop(f *Frame) error {
+	return nil
+}
 
-func popFromStack(code []byte, stack []*big.Int) (*big.Int, []*big.Int) {
-	return stack[0], stack[1:]
+func Push0(f *Frame) error {
+	f.Stack.push(&Word{})
+	return nil
 }
 
-func pushToStack(val *big.Int, stack []*big.Int) []*big.Int {
-	return append([]*big.Int{val}, stack...)
+// makePush returns the handler for PUSH<size>. Immediate bytes past the end of
+// the code read as zero.
+func makePush(size uint64) func(f *Frame) error {
+	return func(f *Frame) error {
+		var val Word
+		start, end := f.PC+1, f.PC+1+size
+		codeLen := uint64(len(f.Code))
+		if start < codeLen {
+			if end > codeLen {
+				val.SetBytes(f.Code[start:codeLen])
+				val.Lsh(&val, uint(end-codeLen)*8)
+			} else {
+				val.SetBytes(f.Code[start:end])
+			}
+		}
+		f.Stack.push(&val)
+		f.PC += size
+		return nil
+	}
 }
 
-func overflow(val *big.Int) *big.Int {
-	return new(big.Int).Mod(val, floatToBigInt(math.Exp2(256)))
+// makeDup returns the handler for DUP<n>.
+func makeDup(n int) func(f *Frame) error {
+	return func(f *Frame) error {
+		f.Stack.dup(n)
+		return nil
+	}
 }
 
-func Push(code []byte, stack []*big.Int) []*big.Int {
-	// Make a new stack with len of original stack
-	n := make([]*big.Int, len(stack))
-	// Copy old stack to new stack
-	copy(n, stack)
-	// Create a new big int value
-	val := new(big.Int)
-	// Set its bytes
-	val.SetBytes(code)
+// makeSwap returns the handler for SWAP<n>.
+func makeSwap(n int) func(f *Frame) error {
+	return func(f *Frame) error {
+		f.Stack.swap(n)
+		return nil
+	}
+}
 
-	// Set new big int value to a new stack, merge with old stack
-	n = append([]*big.Int{val}, stack...)
-	// return new stack
-	return n
+func Add(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Add(&x, y)
+	return nil
 }
 
-func Pop(code []byte, stack []*big.Int) []*big.Int {
-	return stack[1:]
+func Lt(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	setBool(y, x.Lt(y))
+	return nil
 }
 
-func Stop(code []byte, stack []*big.Int) []*big.Int {
-	return stack
+func SLt(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	setBool(y, x.Slt(y))
+	return nil
 }
 
-func Add(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func Gt(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	setBool(y, x.Gt(y))
+	return nil
+}
 
-	var1 = var1.Add(var1, var2)
-	var1 = overflow(var1)
-	return pushToStack(var1, stack)
+func SGt(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	setBool(y, x.Sgt(y))
+	return nil
 }
 
-func Lt(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
-	c := var1.Cmp(var2)
-	if c == 1 || c == 0 {
-		return pushToStack(big.NewInt(0), stack)
-	} else {
-		return pushToStack(big.NewInt(1), stack)
-	}
+func Mul(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Mul(&x, y)
+	return nil
 }
 
-func SLt(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1     *big.Int
-		var2     *big.Int
-		var1Sign bool // Negative true or false
-		var2Sign bool
-	)
-	var1Sign = false
-	var2Sign = false
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func Sub(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Sub(&x, y)
+	return nil
+}
 
-	if isNegative(var1) {
-		var1Sign = true
-		var1 = twosComplement(var1)
-	}
+func Div(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Div(&x, y)
+	return nil
+}
 
-	if isNegative(var2) {
-		var2Sign = true
-		var2 = twosComplement(var2)
-	}
+func SDiv(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.SDiv(&x, y)
+	return nil
+}
 
-	if var1Sign && !var2Sign {
-		return pushToStack(big.NewInt(1), stack)
-	}
+func Mod(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Mod(&x, y)
+	return nil
+}
 
-	if var2Sign && !var1Sign {
-		return pushToStack(big.NewInt(0), stack)
-	}
+func SMod(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.SMod(&x, y)
+	return nil
+}
 
-	c := var1.Cmp(var2)
+func AddMod(f *Frame) error {
+	x, y, m := f.Stack.pop(), f.Stack.pop(), f.Stack.peek()
+	m.AddMod(&x, &y, m)
+	return nil
+}
 
-	// If they're both negative, swap the responses
-	// if var1Sign && var2Sign {
-	// 	if c == 1 || c == 0 {
-	// 		return pushToStack(big.NewInt(1), stack)
-	// 	} else {
-	// 		return pushToStack(big.NewInt(0), stack)
-	// 	}
-	// }
+func MulMod(f *Frame) error {
+	x, y, m := f.Stack.pop(), f.Stack.pop(), f.Stack.peek()
+	m.MulMod(&x, &y, m)
+	return nil
+}
 
-	if c == 1 || c == 0 {
-		return pushToStack(big.NewInt(0), stack)
-	} else {
-		return pushToStack(big.NewInt(1), stack)
-	}
+func Exp(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Exp(&x, y)
+	return nil
 }
 
-func Gt(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
-	c := var1.Cmp(var2)
-	if c == -1 || c == 0 {
-		return pushToStack(big.NewInt(0), stack)
-	} else {
-		return pushToStack(big.NewInt(1), stack)
-	}
+func SignExtend(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.ExtendSign(y, &x)
+	return nil
 }
 
-func SGt(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1     *big.Int
-		var2     *big.Int
-		var1Sign bool // Negative true or false
-		var2Sign bool
-	)
-	var1Sign = false
-	var2Sign = false
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func Eq(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	setBool(y, x.Eq(y))
+	return nil
+}
 
-	if isNegative(var1) {
-		var1Sign = true
-		var1 = twosComplement(var1)
-	}
+func IsZero(f *Frame) error {
+	x := f.Stack.peek()
+	setBool(x, x.IsZero())
+	return nil
+}
 
-	if isNegative(var2) {
-		var2Sign = true
-		var2 = twosComplement(var2)
-	}
+func Not(f *Frame) error {
+	x := f.Stack.peek()
+	x.Not(x)
+	return nil
+}
 
-	if var1Sign && !var2Sign {
-		return pushToStack(big.NewInt(1), stack)
-	}
+func And(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.And(&x, y)
+	return nil
+}
 
-	if var2Sign && !var1Sign {
-		return pushToStack(big.NewInt(0), stack)
-	}
+func Or(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Or(&x, y)
+	return nil
+}
 
-	c := var1.Cmp(var2)
+func Xor(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Xor(&x, y)
+	return nil
+}
 
-	// If they're both negative, swap the responses
-	if var1Sign && var2Sign {
-		if c == -1 {
-			return pushToStack(big.NewInt(1), stack)
-		} else {
-			return pushToStack(big.NewInt(0), stack)
-		}
+// shiftAmount returns shift as a uint, saturating at 256 since every larger
+// shift has the same result.
+func shiftAmount(shift *Word) uint {
+	if !shift.IsUint64() || shift[0] > 256 {
+		return 256
 	}
+	return uint(shift[0])
+}
 
-	if c == -1 || c == 0 {
-		return pushToStack(big.NewInt(0), stack)
-	} else {
-		return pushToStack(big.NewInt(1), stack)
-	}
+func Shl(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Lsh(y, shiftAmount(&x))
+	return nil
 }
 
-func Mul(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func Shr(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Rsh(y, shiftAmount(&x))
+	return nil
+}
 
-	var1 = var1.Mul(var1, var2)
-	var1 = overflow(var1)
-	return pushToStack(var1, stack)
+func Sar(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.SRsh(y, shiftAmount(&x))
+	return nil
 }
 
-func Sub(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func Byte(f *Frame) error {
+	x, y := f.Stack.pop(), f.Stack.peek()
+	y.Byte(y, &x)
+	return nil
+}
 
-	var1 = var1.Sub(var1, var2)
-	var1 = overflow(var1)
-	return pushToStack(var1, stack)
+func MLoad(f *Frame) error {
+	x := f.Stack.peek()
+	x.SetBytes(f.Memory.GetPtr(x.Uint64(), 32))
+	return nil
 }
 
-func Div(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func MStore(f *Frame) error {
+	offset, val := f.Stack.pop(), f.Stack.pop()
+	f.Memory.Set32(offset.Uint64(), &val)
+	return nil
+}
 
-	if var2.BitLen() == 0 {
-		return pushToStack(var2, stack)
-	} else {
-		var1 = var1.Div(var1, var2)
-		var1 = overflow(var1)
-		return pushToStack(var1, stack)
-	}
+func MStore8(f *Frame) error {
+	offset, val := f.Stack.pop(), f.Stack.pop()
+	f.Memory.SetByte(offset.Uint64(), byte(val[0]))
+	return nil
 }
 
-func twosComplement(n *big.Int) *big.Int {
-	// Check if n is zero
-	if n.Sign() == 0 {
-		return n
-	}
+func MSize(f *Frame) error {
+	f.Stack.push(NewWord(uint64(f.Memory.Len())))
+	return nil
+}
 
-	// Calculate the maximum value for the given bit size of n
-	max := new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(n.BitLen())), nil)
+func pushAddress(f *Frame, a Address) {
+	w := a.Word()
+	f.Stack.push(&w)
+}
 
-	// Subtract n from the maximum value
-	return new(big.Int).Sub(max, n)
+func Sha3(f *Frame) error {
+	offset, size := f.Stack.pop(), f.Stack.peek()
+	hash := Keccak256(f.Memory.GetPtr(offset.Uint64(), size.Uint64()))
+	size.SetBytes(hash[:])
+	return nil
 }
 
-func SDiv(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1     *big.Int
-		var2     *big.Int
-		var1Sign bool
-		var2Sign bool
-	)
-	var1Sign = false
-	var2Sign = false
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func AddressOp(f *Frame) error {
+	pushAddress(f, f.Address)
+	return nil
+}
+
+func Balance(f *Frame) error {
+	slot := f.Stack.peek()
+	*slot = f.in.state.GetBalance(slot.Bytes20())
+	return nil
+}
 
-	if isNegative(var1) {
-		var1Sign = true
-		var1 = twosComplement(var1)
+// dataOffset returns offset as a uint64, saturating at math.MaxUint64. It is
+// for offsets into data that is read with zero padding, where every offset
+// past the end reads zeros.
+func dataOffset(offset *Word) uint64 {
+	if !offset.IsUint64() {
+		return math.MaxUint64
 	}
+	return offset[0]
+}
 
-	if isNegative(var2) {
-		var2Sign = true
-		var2 = twosComplement(var2)
+func CallDataLoad(f *Frame) error {
+	x := f.Stack.peek()
+	var data [32]byte
+	if offset := dataOffset(x); offset < uint64(len(f.Input)) {
+		copy(data[:], f.Input[offset:])
 	}
+	x.SetBytes(data[:])
+	return nil
+}
 
-	if var2.BitLen() == 0 {
-		return pushToStack(var2, stack)
-	} else {
-		var1 = var1.Div(var1, var2)
-		var1 = overflow(var1)
-		if var1Sign != var2Sign {
-			var1 = flipToNegative(var1)
-		}
-		return pushToStack(var1, stack)
+func CallDataSize(f *Frame) error {
+	f.Stack.push(NewWord(uint64(len(f.Input))))
+	return nil
+}
+
+func CallDataCopy(f *Frame) error {
+	memOffset, offset, size := f.Stack.pop(), f.Stack.pop(), f.Stack.pop()
+	if !size.IsZero() {
+		f.Memory.SetPadded(memOffset.Uint64(), size.Uint64(), f.Input, dataOffset(&offset))
 	}
+	return nil
 }
 
-func SMod(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1     *big.Int
-		var2     *big.Int
-		var1Sign bool
-		var2Sign bool
-	)
-	var1Sign = false
-	var2Sign = false
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func CodeSize(f *Frame) error {
+	f.Stack.push(NewWord(uint64(len(f.Code))))
+	return nil
+}
 
-	if isNegative(var1) {
-		var1Sign = true
-		var1 = twosComplement(var1)
+func CodeCopy(f *Frame) error {
+	memOffset, offset, size := f.Stack.pop(), f.Stack.pop(), f.Stack.pop()
+	if !size.IsZero() {
+		f.Memory.SetPadded(memOffset.Uint64(), size.Uint64(), f.Code, dataOffset(&offset))
 	}
+	return nil
+}
 
-	if isNegative(var2) {
-		var2Sign = true
-		var2 = twosComplement(var2)
+func ExtCodeSize(f *Frame) error {
+	slot := f.Stack.peek()
+	slot.SetUint64(uint64(len(f.in.state.GetCode(slot.Bytes20()))))
+	return nil
+}
+
+func ExtCodeCopy(f *Frame) error {
+	addr, memOffset, offset, size := f.Stack.pop(), f.Stack.pop(), f.Stack.pop(), f.Stack.pop()
+	if !size.IsZero() {
+		code := f.in.state.GetCode(addr.Bytes20())
+		f.Memory.SetPadded(memOffset.Uint64(), size.Uint64(), code, dataOffset(&offset))
 	}
+	return nil
+}
 
-	var res *big.Int
-	if var2.BitLen() == 0 {
-		res = new(big.Int).Mod(var2, var1)
-		return pushToStack(res, stack)
+// ExtCodeHash pushes zero for empty accounts, as EIP-1052 treats them like
+// accounts that do not exist.
+func ExtCodeHash(f *Frame) error {
+	slot := f.Stack.peek()
+	addr := Address(slot.Bytes20())
+	if f.in.state.Empty(addr) {
+		slot.Clear()
 	} else {
-		res = new(big.Int).Mod(var1, var2)
+		*slot = f.in.state.GetCodeHash(addr)
 	}
+	return nil
+}
+
+func ReturnDataSize(f *Frame) error {
+	f.Stack.push(NewWord(uint64(len(f.LastReturnData))))
+	return nil
+}
 
-	if var1Sign || var2Sign {
-		res = flipToNegative(res)
+// ReturnDataCopy fails when asked for bytes past the end of the return data
+// instead of padding them with zeros like the other *COPY instructions
+// (EIP-211).
+func ReturnDataCopy(f *Frame) error {
+	memOffset, offset, size := f.Stack.pop(), f.Stack.pop(), f.Stack.pop()
+	end := new(Word).Add(&offset, &size)
+	if end.Lt(&offset) || !end.IsUint64() || end[0] > uint64(len(f.LastReturnData)) {
+		return ErrReturnDataOutOfBounds
 	}
-	return pushToStack(res, stack)
+	if !size.IsZero() {
+		f.Memory.Set(memOffset.Uint64(), f.LastReturnData[offset[0]:end[0]])
+	}
+	return nil
 }
 
-func flipToNegative(v *big.Int) *big.Int {
-	y := floatToBigInt(math.Exp2(256))
-	y = y.Sub(y, v)
-	return y
+func Origin(f *Frame) error {
+	pushAddress(f, f.tx.Origin)
+	return nil
 }
 
-func Mod(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func Caller(f *Frame) error {
+	pushAddress(f, f.Caller)
+	return nil
+}
 
-	var res *big.Int
-	if var2.BitLen() == 0 {
-		res = new(big.Int).Mod(var2, var1)
+func CallValue(f *Frame) error {
+	f.Stack.push(&f.Value)
+	return nil
+}
+
+func GasPrice(f *Frame) error {
+	f.Stack.push(&f.tx.GasPrice)
+	return nil
+}
+
+func BlockHash(f *Frame) error {
+	num := f.Stack.peek()
+	var lower uint64
+	if upper := f.block.Number; upper > 256 {
+		lower = upper - 256
+	}
+	if f.block.GetHash != nil && num.IsUint64() && lower <= num[0] && num[0] < f.block.Number {
+		*num = f.block.GetHash(num[0])
 	} else {
-		res = new(big.Int).Mod(var1, var2)
+		num.Clear()
 	}
-	return pushToStack(res, stack)
+	return nil
 }
 
-func AddMod(code []byte, stack []*big.Int) []*big.Int {
-	stack = Add(code, stack)
-	stack = Mod(code, stack)
-	return stack
+func Coinbase(f *Frame) error {
+	pushAddress(f, f.block.Coinbase)
+	return nil
 }
 
-func MulMod(code []byte, stack []*big.Int) []*big.Int {
-	// stack = Mul(code, stack)
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func Timestamp(f *Frame) error {
+	f.Stack.push(NewWord(f.block.Timestamp))
+	return nil
+}
 
-	var1 = var1.Mul(var1, var2)
-	stack = pushToStack(var1, stack)
-	stack = Mod(code, stack)
+func Number(f *Frame) error {
+	f.Stack.push(NewWord(f.block.Number))
+	return nil
+}
+
+func Difficulty(f *Frame) error {
+	f.Stack.push(&f.block.Difficulty)
+	return nil
+}
+
+// PrevRandao replaces Difficulty from the Merge (EIP-4399).
+func PrevRandao(f *Frame) error {
+	f.Stack.push(&f.block.Random)
+	return nil
+}
 
-	var1, stack = popFromStack(code, stack)
-	var1 = overflow(var1)
-	return pushToStack(var1, stack)
+func GasLimit(f *Frame) error {
+	f.Stack.push(NewWord(f.block.GasLimit))
+	return nil
 }
 
-func Exp(code []byte, stack []*big.Int) []*big.Int {
-	// stack = Mul(code, stack)
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
+func ChainID(f *Frame) error {
+	f.Stack.push(&f.block.ChainID)
+	return nil
+}
+
+func SelfBalance(f *Frame) error {
+	balance := f.in.state.GetBalance(f.Address)
+	f.Stack.push(&balance)
+	return nil
+}
 
-	var1 = new(big.Int).Exp(var1, var2, big.NewInt(0))
-	var1 = overflow(var1)
-	return pushToStack(var1, stack)
+func BaseFee(f *Frame) error {
+	f.Stack.push(&f.block.BaseFee)
+	return nil
 }
 
-func checkFirstBit(s string) int {
-	r, _ := utf8.DecodeRuneInString(s)
-	if string(r) == "0" {
-		return 0
+func BlobHash(f *Frame) error {
+	index := f.Stack.peek()
+	if hashes := f.tx.BlobHashes; index.IsUint64() && index[0] < uint64(len(hashes)) {
+		*index = hashes[index[0]]
 	} else {
-		return 1
+		index.Clear()
 	}
-	// return string(r) == "0"? 0 : 1
+	return nil
 }
 
-func paddedBinary(x *big.Int, n int) string {
-	// res := fmt.Sprintf("%0*b", n, x)
-	s := strings.Repeat("0", n)
-	return s
+func BlobBaseFee(f *Frame) error {
+	f.Stack.push(&f.block.BlobBaseFee)
+	return nil
 }
 
-// Pads a big.Int to 32 bytes (64 hex digits)
-func pad32(x *big.Int) string {
-	len := len(x.Text(16))
-	s := strings.Repeat("0", 64-len)
-	s = s + x.Text(16)
-	return s
+func SLoad(f *Frame) error {
+	loc := f.Stack.peek()
+	*loc = f.in.state.GetState(f.Address, loc)
+	return nil
 }
 
-func padNegative(x *big.Int, n int) string {
-	// res := fmt.Sprintf("%0*b", n, x)
-	s := strings.Repeat("1", n)
-	return s
+func SStore(f *Frame) error {
+	loc, val := f.Stack.pop(), f.Stack.pop()
+	f.in.state.SetState(f.Address, &loc, &val)
+	return nil
 }
 
-func FullBinary(x *big.Int) string {
-	// Convert the big.Int to a binary string
-	s := x.Text(2)
-
-	// Pad the binary string with zeros to make it 32 bits
-	s = strings.Repeat("0", 32-len(s)) + s
+func TLoad(f *Frame) error {
+	loc := f.Stack.peek()
+	*loc = f.in.state.GetTransientState(f.Address, loc)
+	return nil
+}
 
-	return s
+func TStore(f *Frame) error {
+	loc, val := f.Stack.pop(), f.Stack.pop()
+	f.in.state.SetTransientState(f.Address, &loc, &val)
+	return nil
 }
 
-func isNegative(value *big.Int) bool {
-	hold := make([]big.Word, value.BitLen())
-	copy(hold, value.Bits())
-	binaryLength := len(value.Text(2))
-	additionalLength := 0
-	if binaryLength%8 != 0 {
-		additionalLength = 8 - binaryLength%8
-	}
-	binaryString := paddedBinary(value, additionalLength)
-	if checkFirstBit(binaryString) == 0 {
-		return false
-	} else {
-		return true
-	}
-}
-
-func SignNumber(value *big.Int) *big.Int {
-	hold := make([]big.Word, value.BitLen())
-	copy(hold, value.Bits())
-	res := padNegative(big.NewInt(0).SetBits(hold), 256)
-	x, _ := new(big.Int).SetString(res, 2)
-	return x
-}
-
-func SignExtend(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		value *big.Int
-	)
-	_, stack = popFromStack(code, stack)
-	value, stack = popFromStack(code, stack)
-	hold := make([]big.Word, value.BitLen())
-	copy(hold, value.Bits())
-	binaryLength := len(value.Text(2))
-	additionalLength := 0
-	if binaryLength%8 != 0 {
-		additionalLength = 8 - binaryLength%8
-	}
-	binaryString := paddedBinary(value, additionalLength)
-	if checkFirstBit(binaryString) == 0 {
-		stack = pushToStack(big.NewInt(0).SetBits(hold), stack)
-		return stack
-	} else {
-		res := padNegative(big.NewInt(0).SetBits(hold), 256)
-		x, _ := new(big.Int).SetString(res, 2)
-		return pushToStack(x, stack)
-	}
-}
-
-func SignExtendSingle(value *big.Int) *big.Int {
-	hold := make([]big.Word, value.BitLen())
-	copy(hold, value.Bits())
-	// binaryLength := len(value.Text(2))
-	// additionalLength := 0
-	// if binaryLength%8 != 0 {
-	// 	additionalLength = 8 - binaryLength%8
-	// }
-	// binaryString := paddedBinary(value, additionalLength)
-	// if checkFirstBit(binaryString) == 0 {
-	// 	return big.NewInt(0).SetBits(hold)
-	// } else {
-	res := padNegative(big.NewInt(0).SetBits(hold), 256)
-	x, _ := new(big.Int).SetString(res, 2)
-	return x
-	// }
-}
-
-func Eq(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
-
-	c := var1.Cmp(var2)
-	if c == 0 {
-		return pushToStack(big.NewInt(1), stack)
-	} else {
-		return pushToStack(big.NewInt(0), stack)
+func MCopy(f *Frame) error {
+	dst, src, size := f.Stack.pop(), f.Stack.pop(), f.Stack.pop()
+	if !size.IsZero() {
+		f.Memory.Copy(dst.Uint64(), src.Uint64(), size.Uint64())
 	}
+	return nil
 }
 
-func IsZero(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
+func Jump(f *Frame) error {
+	dest := f.Stack.pop()
+	return f.jump(&dest)
+}
 
-	c := var1.Cmp(big.NewInt(0))
-	if c == 0 {
-		return pushToStack(big.NewInt(1), stack)
-	} else {
-		return pushToStack(big.NewInt(0), stack)
+func JumpI(f *Frame) error {
+	dest, cond := f.Stack.pop(), f.Stack.pop()
+	if cond.IsZero() {
+		f.PC++
+		return nil
 	}
+	return f.jump(&dest)
 }
 
-func Not(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	max := floatToBigInt(math.Exp2(256))
-	max = max.Sub(max, big.NewInt(1))
-	var1 = max.Sub(max, var1)
+func JumpDest(f *Frame) error {
+	return nil
+}
 
-	return pushToStack(var1, stack)
+func PC(f *Frame) error {
+	f.Stack.push(NewWord(f.PC))
+	return nil
+}
 
-}
-
-func And(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
-	// max := floatToBigInt(math.Exp2(256))
-	// max = max.Sub(max, big.NewInt(1))
-	var1 = var2.And(var2, var1)
-	return pushToStack(var1, stack)
-}
-
-func Or(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
-
-	var1 = var2.Or(var2, var1)
-	return pushToStack(var1, stack)
-}
-
-func Xor(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
-
-	var1 = var2.Xor(var2, var1)
-	return pushToStack(var1, stack)
-}
-
-func Shl(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
-
-	max := floatToBigInt(math.Exp2(256))
-	max = max.Sub(max, big.NewInt(1))
-
-	// If we're pushing by more than 1024 (16 * 64) number of Fs in hex
-	if var1.Cmp(big.NewInt(1024)) == 1 {
-		return pushToStack(big.NewInt(0), stack)
-	}
-	var1 = var2.Lsh(var2, uint(var1.Int64()))
-
-	res := new(big.Int).And(var1, max)
-	return pushToStack(res, stack)
-}
-
-func Shr(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		var1 *big.Int
-		var2 *big.Int
-	)
-	var1, stack = popFromStack(code, stack)
-	var2, stack = popFromStack(code, stack)
-
-	var1 = var2.Rsh(var2, uint(var1.Int64()))
-	return pushToStack(var1, stack)
-}
-
-func FillOnes(v *big.Int, bits int) *big.Int {
-	max := floatToBigInt(math.Exp2(256))
-	max = max.Sub(max, big.NewInt(1))
-	add := max.Lsh(max, 256-uint(bits))
-	add = overflow(add)
-	return v.Or(add, v)
-}
-
-func Sar(code []byte, stack []*big.Int) []*big.Int {
-	max := floatToBigInt(math.Exp2(256))
-	max = max.Sub(max, big.NewInt(1))
-	var (
-		shiftAmt *big.Int
-		base     *big.Int
-	)
-	shiftAmt, stack = popFromStack(code, stack)
-	base, stack = popFromStack(code, stack)
-
-	// If we're pushing by more than 1024 (16 * 64) number of Fs in hex
-	if shiftAmt.Cmp(big.NewInt(1024)) == 1 {
-		fmt.Printf("========================\n")
-		fmt.Printf("|| %s \n|| %d \n|| %s  \n", shiftAmt.Text(16), base.Bit(256), max.Text(16))
-		fmt.Printf("========================")
-		// If the highest bit is a 1, push all 1s
-		if base.Bit(255) == 1 {
-			return pushToStack(max, stack)
-			// If it is a 0, push 0
-		} else {
-			return pushToStack(big.NewInt(0), stack)
-		}
+func Gas(f *Frame) error {
+	if f.in.unlimitedGas {
+		var max Word
+		f.Stack.push(max.SetAllOne())
+		return nil
 	}
-	var res *big.Int
-	if isNegative(base) {
-		base.Rsh(base, uint(shiftAmt.Int64()))
-		res = FillOnes(base, int(shiftAmt.Int64()))
-	} else {
-		res = base.Rsh(base, uint(shiftAmt.Int64()))
-	}
-	return pushToStack(res, stack)
-}
-
-func Byte(code []byte, stack []*big.Int) []*big.Int {
-	var (
-		byteValue *big.Int
-		number    *big.Int
-	)
-	// Byte is 2x 1 Hex value: aka FF is one byte
-	byteValue, stack = popFromStack(code, stack)
-	// So 30th Byte is really 60th value in the hex string
-	byteValue = byteValue.Mul(byteValue, big.NewInt(2))
-	number, stack = popFromStack(code, stack)
-	numberString := pad32(number)
-
-	// If byte value is bigger than 64 (32 bytes) push 0
-	if byteValue.Cmp(big.NewInt(64)) == 1 {
-		return pushToStack(big.NewInt(0), stack)
-	}
-
-	// Set res to the numberString
-	res := numberString
-
-	// Clip number string by the byte value -> + 2 because we are looking at 2 hex values
-	res = res[byteValue.Int64() : byteValue.Int64()+2]
-	ret, _ := big.NewInt(0).SetString("0x"+res, 0)
-	return pushToStack(ret, stack)
-}
-
-func Dup(val *big.Int, stack []*big.Int) []*big.Int {
-	// Make a new stack with len of original stack
-	n := make([]*big.Int, len(stack))
-	// Copy old stack to new stack
-	copy(n, stack)
-	// Set new big int value to a new stack, merge with old stack
-	n = append([]*big.Int{val}, stack...)
-	// return new stack
-	return n
-}
-
-// func Swap(val int, stack []*big.Int) []*big.Int {
-// 	// [1 2 3]
-// 	// [1 3 2]
-// 	// Make a new stack with len of original stack
-// 	n := make([]*big.Int, len(stack))
-// 	// Copy old stack to new stack
-// 	copy(n, stack)
-// 	// Set new big int value to a new stack, merge with old stack
-// 	n = append([]*big.Int{val}, stack...)
-// 	// return new stack
-// 	return n
-// }
-
-func Swap(index int, stack []*big.Int) []*big.Int {
-
-	var (
-		top  *big.Int
-		swap *big.Int
-		n    []*big.Int
-	)
-
-	// Check if the index is within the bounds of the stack
-	if index < 0 || index >= len(stack) {
-		return stack
-	}
-
-	top = stack[0]
-	swap = stack[index]
-
-	// new stack
-	for i := 0; i < len(stack); i++ {
-		if i == 0 {
-			n = append(n, swap)
-		} else if i == index {
-			n = append(n, top)
-		} else {
-			n = append(n, stack[i])
+	f.Stack.push(NewWord(f.Gas))
+	return nil
+}
+
+// makeLog returns the handler for LOG<n>.
+func makeLog(n int) func(f *Frame) error {
+	return func(f *Frame) error {
+		offset, size := f.Stack.pop(), f.Stack.pop()
+		topics := make([]Word, n)
+		for i := range topics {
+			topics[i] = f.Stack.pop()
+		}
+		f.in.state.AddLog(&Log{
+			Address: f.Address,
+			Topics:  topics,
+			Data:    f.Memory.GetCopy(offset.Uint64(), size.Uint64()),
+		})
+		return nil
+	}
+}
+
+func Return(f *Frame) error {
+	offset, size := f.Stack.pop(), f.Stack.pop()
+	f.ReturnData = f.Memory.GetCopy(offset.Uint64(), size.Uint64())
+	return nil
+}
+
+// Revert ends execution like Return, but fails it so that its state changes
+// are undone. Unlike other failures it leaves the remaining gas unused.
+func Revert(f *Frame) error {
+	offset, size := f.Stack.pop(), f.Stack.pop()
+	f.ReturnData = f.Memory.GetCopy(offset.Uint64(), size.Uint64())
+	return ErrExecutionReverted
+}
+
+// finishCall completes a CALL-type instruction: it pushes whether the call
+// succeeded, copies its output into the window the caller reserved for it,
+// and takes back the gas the call did not use.
+func (f *Frame) finishCall(ret []byte, returnGas uint64, err error, retOffset, retSize *Word) {
+	f.captureCallFailure(err)
+	f.LastReturnData = ret
+	var success Word
+	setBool(&success, err == nil)
+	f.Stack.push(&success)
+	if (err == nil || err == ErrExecutionReverted) && !retSize.IsZero() {
+		if n := retSize.Uint64(); uint64(len(ret)) > n {
+			ret = ret[:n]
 		}
+		f.Memory.Set(retOffset.Uint64(), ret)
 	}
-	return n
+	f.Gas += returnGas
 }
 
-// Run runs the EVM code and returns the stack and a success indicator.
-func Evm(code []byte) ([]*big.Int, bool) {
-	var stack []*big.Int
-	var remainder []byte
-	funcs, bytes = buildMaps()
-	overallpc := 0
-	var memory []*big.Int
-
-Opcode:
-	pc := 0
-
-	op := code[pc] // First bytes in code
-	if op == 0 {
-		goto Done
+// captureCallFailure tells the CallTracer, if there is one, that the call or
+// create f is executing failed with err.
+func (f *Frame) captureCallFailure(err error) {
+	if err == nil {
+		return
 	}
-
-	if op == 0xfe {
-		// Invalid opcode
-		return nil, false
+	if t, ok := f.in.tracer.(CallTracer); ok {
+		t.CaptureCallFailure(f.PC, f.op(), haltReason(err), err, f)
 	}
+}
 
-	if op == 0x58 {
-		fmt.Printf("========================\n")
-		fmt.Printf("%d || %d || %d \n", overallpc, op, stack)
-		fmt.Printf("========================\n")
-		// PC
-
-		remainder = code[1:]
-		stack = pushToStack(big.NewInt(int64(overallpc)), stack)
-	} else if op == 0x5a {
-		max := floatToBigInt(math.Exp2(256))
-		max = max.Sub(max, big.NewInt(1))
-		remainder = code[1:]
-		stack = pushToStack(max, stack)
-	} else if 96 <= op && op <= 127 {
-		// PUSH1 - PUSH32
-		size := int(op) - 96 + 1
-		val := code[pc+1 : pc+1+int(size)]
-		remainder = code[pc+1+int(size):]
-		stack = Push(val, stack)
-		overallpc += 2
-	} else if 128 <= op && op <= 143 {
-		// DUP1 - DUP16
-		index := int(op) - 128
-		// size := int(op) - 96 + 1
-		val := stack[index]
-		remainder = code[pc+1:]
-		stack = Dup(val, stack)
-	} else if 144 <= op && op <= 159 {
-		// DUP1 - DUP16
-		index := int(op) - 144
-		// size := int(op) - 96 + 1
-		remainder = code[pc+1:]
-		stack = Swap(index+1, stack)
+// createGas takes the gas for a create out of f: all of it before
+// TangerineWhistle, and all but one 64th after (EIP-150).
+func (f *Frame) createGas() uint64 {
+	gas := f.Gas
+	if f.in.rules.IsEIP150 {
+		gas -= gas / 64
+	}
+	f.Gas -= gas
+	return gas
+}
 
+// finishCreate completes CREATE or CREATE2 like finishCall, pushing the new
+// contract's address, or zero if the create failed.
+func (f *Frame) finishCreate(ret []byte, addr Address, returnGas uint64, err error) {
+	f.captureCallFailure(err)
+	// Before Homestead, a create whose code could not be paid for still
+	// creates an account.
+	if err == nil || (!f.in.rules.IsHomestead && err == ErrCodeStoreOutOfGas) {
+		pushAddress(f, addr)
 	} else {
-		size := bytes[op]
-		val := code[pc+1 : pc+1+int(size)]
-		remainder = code[pc+1+int(size):]
-		stack = funcs[op](val, stack)
-		overallpc += 1
-	}
-	if len(remainder) > 0 {
-		code = remainder
-		goto Opcode
+		f.Stack.push(new(Word))
+	}
+	if err == ErrExecutionReverted {
+		f.LastReturnData = ret
 	} else {
-		return stack, true
+		f.LastReturnData = nil
+	}
+	f.Gas += returnGas
+}
+
+func Create(f *Frame) error {
+	value, offset, size := f.Stack.pop(), f.Stack.pop(), f.Stack.pop()
+	code := f.Memory.GetCopy(offset.Uint64(), size.Uint64())
+	addr := CreateAddress(f.Address, f.in.state.GetNonce(f.Address))
+	ret, returnGas, err := f.create(code, f.createGas(), &value, addr)
+	f.finishCreate(ret, addr, returnGas, err)
+	return nil
+}
+
+func Create2(f *Frame) error {
+	value, offset, size, salt := f.Stack.pop(), f.Stack.pop(), f.Stack.pop(), f.Stack.pop()
+	code := f.Memory.GetCopy(offset.Uint64(), size.Uint64())
+	addr := CreateAddress2(f.Address, salt.Bytes32(), Keccak256(code))
+	ret, returnGas, err := f.create(code, f.createGas(), &value, addr)
+	f.finishCreate(ret, addr, returnGas, err)
+	return nil
+}
+
+func Call(f *Frame) error {
+	f.Stack.pop() // requested gas, already worked out in callGasTemp
+	addr, value := f.Stack.pop(), f.Stack.pop()
+	inOffset, inSize := f.Stack.pop(), f.Stack.pop()
+	retOffset, retSize := f.Stack.pop(), f.Stack.pop()
+	if f.readOnly && !value.IsZero() {
+		return ErrWriteProtection
+	}
+	gas := f.callGasTemp
+	if !value.IsZero() {
+		gas += CallStipend
+	}
+	input := f.Memory.GetCopy(inOffset.Uint64(), inSize.Uint64())
+	ret, returnGas, err := f.call(addr.Bytes20(), input, gas, &value)
+	f.finishCall(ret, returnGas, err, &retOffset, &retSize)
+	return nil
+}
+
+func CallCode(f *Frame) error {
+	f.Stack.pop() // requested gas, already worked out in callGasTemp
+	addr, value := f.Stack.pop(), f.Stack.pop()
+	inOffset, inSize := f.Stack.pop(), f.Stack.pop()
+	retOffset, retSize := f.Stack.pop(), f.Stack.pop()
+	gas := f.callGasTemp
+	if !value.IsZero() {
+		gas += CallStipend
+	}
+	input := f.Memory.GetCopy(inOffset.Uint64(), inSize.Uint64())
+	ret, returnGas, err := f.callCode(addr.Bytes20(), input, gas, &value)
+	f.finishCall(ret, returnGas, err, &retOffset, &retSize)
+	return nil
+}
+
+func DelegateCall(f *Frame) error {
+	f.Stack.pop() // requested gas, already worked out in callGasTemp
+	addr := f.Stack.pop()
+	inOffset, inSize := f.Stack.pop(), f.Stack.pop()
+	retOffset, retSize := f.Stack.pop(), f.Stack.pop()
+	input := f.Memory.GetCopy(inOffset.Uint64(), inSize.Uint64())
+	ret, returnGas, err := f.delegateCall(addr.Bytes20(), input, f.callGasTemp)
+	f.finishCall(ret, returnGas, err, &retOffset, &retSize)
+	return nil
+}
+
+func StaticCall(f *Frame) error {
+	f.Stack.pop() // requested gas, already worked out in callGasTemp
+	addr := f.Stack.pop()
+	inOffset, inSize := f.Stack.pop(), f.Stack.pop()
+	retOffset, retSize := f.Stack.pop(), f.Stack.pop()
+	input := f.Memory.GetCopy(inOffset.Uint64(), inSize.Uint64())
+	ret, returnGas, err := f.staticCall(addr.Bytes20(), input, f.callGasTemp)
+	f.finishCall(ret, returnGas, err, &retOffset, &retSize)
+	return nil
+}
+
+// SelfDestruct sends the balance of the executing account to a beneficiary
+// and deletes the account at the end of the transaction. From Cancun, the
+// account is only deleted if it was created in the same transaction
+// (EIP-6780); otherwise just the balance moves.
+func SelfDestruct(f *Frame) error {
+	addr := f.Stack.pop()
+	beneficiary := addr.Bytes20()
+	db := f.in.state
+	balance := db.GetBalance(f.Address)
+	if f.in.rules.IsCancun && !f.in.immediateSelfDestruct && !db.CreatedInTx(f.Address) {
+		db.SetBalance(f.Address, new(Word))
+		total := db.GetBalance(beneficiary)
+		db.SetBalance(beneficiary, total.Add(&total, &balance))
+		return nil
+	}
+	// A beneficiary that is the account itself loses the balance.
+	total := db.GetBalance(beneficiary)
+	db.SetBalance(beneficiary, total.Add(&total, &balance))
+	db.Suicide(f.Address)
+	if f.in.immediateSelfDestruct {
+		db.Finalise()
+	}
+	return nil
+}
+
+// Evm runs code with an empty execution context and world state and returns
+// the final stack, top of stack first, and whether execution succeeded. If it
+// failed, the stack is nil. It is a thin wrapper around Interpreter.Run kept
+// for existing callers.
+func Evm(code []byte) ([]*big.Int, bool) {
+	res := NewInterpreter(WithUnlimitedGas()).Run(&ExecutionContext{Code: code})
+	if res.Failed() {
+		return nil, false
+	}
+	stack := make([]*big.Int, len(res.Stack))
+	for i := range res.Stack {
+		stack[i] = res.Stack[i].ToBig()
 	}
-
-Done:
 	return stack, true
-
-}
-
-func buildMaps() (FunctionMap, BytesMap) {
-	funcs = make(map[byte]func(code []byte, stack []*big.Int) []*big.Int)
-	bytes = make(map[byte]int)
-
-	funcs[0] = Stop
-	bytes[0] = 0
-
-	funcs[80] = Pop
-	bytes[80] = 0
-
-	funcs[1] = Add
-	bytes[1] = 0
-
-	funcs[2] = Mul
-	bytes[2] = 0
-
-	funcs[3] = Sub
-	bytes[3] = 0
-
-	funcs[4] = Div
-	bytes[4] = 0
-
-	funcs[5] = SDiv
-	bytes[5] = 0
-
-	funcs[6] = Mod
-	bytes[6] = 0
-
-	funcs[7] = SMod
-	bytes[7] = 0
-
-	funcs[8] = AddMod
-	bytes[8] = 0
-
-	funcs[9] = MulMod
-	bytes[9] = 0
-
-	funcs[10] = Exp
-	bytes[10] = 0
-
-	funcs[11] = SignExtend
-	bytes[11] = 0
-
-	funcs[16] = Lt
-	bytes[16] = 0
-
-	funcs[17] = Gt
-	bytes[17] = 0
-
-	funcs[18] = SLt
-	bytes[18] = 0
-
-	funcs[19] = SGt
-	bytes[19] = 0
-
-	funcs[20] = Eq
-	bytes[20] = 0
-
-	funcs[21] = IsZero
-	bytes[21] = 0
-
-	funcs[22] = And
-	bytes[22] = 0
-
-	funcs[23] = Or
-	bytes[23] = 0
-
-	funcs[24] = Xor
-	bytes[24] = 0
-
-	funcs[25] = Not
-	bytes[25] = 0
-
-	funcs[26] = Byte
-	bytes[26] = 0
-
-	funcs[27] = Shl
-	bytes[27] = 0
-
-	funcs[28] = Shr
-	bytes[28] = 0
-
-	funcs[29] = Sar
-	bytes[29] = 0
-
-	return funcs, bytes
 }
 
-func floatToBigInt(val float64) *big.Int {
-	bigval := new(big.Float)
-	bigval.SetFloat64(val)
-
-	coin := new(big.Float)
-	coin.SetInt(big.NewInt(1))
-
-	bigval.Mul(bigval, coin)
+// Run executes ctx.Code and returns the result.
+func (in *Interpreter) Run(ctx *ExecutionContext) *Result {
+	if ctx.Fork != nil && *ctx.Fork != in.rules.Fork {
+		in = in.withFork(*ctx.Fork)
+	}
+	if in.table == nil {
+		return &Result{Halt: HaltUnknown, Err: ErrUnsupportedFork}
+	}
+	gas := ctx.Gas
+	if in.unlimitedGas {
+		gas = math.MaxUint64
+	}
+	in.state.Prepare()
+	in.state.AddAddressToAccessList(ctx.Origin)
+	in.state.AddAddressToAccessList(ctx.Caller)
+	in.state.AddAddressToAccessList(ctx.Address)
+	if in.rules.IsShanghai {
+		// The coinbase starts warm from Shanghai (EIP-3651).
+		in.state.AddAddressToAccessList(ctx.Coinbase)
+	}
+	snapshot := in.state.Snapshot()
+
+	tx, block := ctx.TxContext, ctx.BlockContext
+	f := &Frame{
+		CallContext: ctx.CallContext,
+		Code:        ctx.Code,
+		Gas:         gas,
+		Stack:       newStack(),
+		Memory:      newMemory(),
+		in:          in,
+		tx:          &tx,
+		block:       &block,
+	}
+	res := &Result{}
+	if err := in.execute(f, snapshot); err != nil {
+		res.Halt = haltReason(err)
+		res.Err = &ExecutionError{Reason: res.Halt, PC: f.PC, Op: f.op(), Err: err}
+	} else {
+		res.Stack = f.Stack.topFirst()
+	}
+	res.ReturnData = f.ReturnData
+	res.Logs = in.state.Logs()
+	res.GasUsed = gas - f.Gas
+	res.Refund = in.refund(res.GasUsed)
+	in.state.Finalise()
+	return res
+}
+
+// refund returns the gas refunded at the end of a transaction that used
+// gasUsed, which is capped at a fraction of gasUsed.
+func (in *Interpreter) refund(gasUsed uint64) uint64 {
+	quotient := RefundQuotient
+	if in.rules.IsLondon {
+		quotient = RefundQuotientEIP3529
+	}
+	refund := in.state.GetRefund()
+	if max := gasUsed / quotient; refund > max {
+		refund = max
+	}
+	return refund
+}
+
+// run executes f from its current program counter until it halts. Running off
+// the end of the code is an implicit STOP.
+func (in *Interpreter) run(f *Frame) error {
+	for {
+		op := f.op()
+		operation := &in.table[op]
+		if operation.execute == nil {
+			return ErrInvalidOpcode
+		}
+		if f.readOnly && operation.writes {
+			return ErrWriteProtection
+		}
+		if sLen := f.Stack.Len(); sLen < operation.minStack {
+			return ErrStackUnderflow
+		} else if sLen > operation.maxStack {
+			return ErrStackOverflow
+		}
+		gas, cost := f.Gas, operation.constantGas
+		if err := f.useGas(cost); err != nil {
+			return err
+		}
 
-	result := new(big.Int)
-	bigval.Int(result) // store converted number in result
+		// Work out how much memory the instruction touches and charge for
+		// expanding it, together with any other dynamic cost, before the
+		// instruction runs so that running out of gas leaves memory as is.
+		var memorySize uint64
+		if operation.memorySize != nil {
+			size, err := operation.memorySize(f.Stack)
+			if err != nil {
+				return err
+			}
+			if size > maxMemorySize {
+				return ErrGasUintOverflow
+			}
+			if in.unlimitedGas && size > unlimitedMemorySize {
+				return ErrOutOfGas
+			}
+			memorySize = toWordSize(size) * 32
+		}
+		if operation.dynamicGas != nil {
+			dynamicCost, err := operation.dynamicGas(f, memorySize)
+			if err != nil {
+				return err
+			}
+			if err := f.useGas(dynamicCost); err != nil {
+				return err
+			}
+			cost += dynamicCost
+		}
+		if in.tracer != nil {
+			in.tracer.CaptureState(f.PC, op, gas, cost, f)
+		}
+		if memorySize > 0 {
+			f.Memory.Resize(memorySize)
+		}
 
-	return result
+		if err := operation.execute(f); err != nil {
+			return err
+		}
+		if operation.halts {
+			return nil
+		}
+		if !operation.jumps {
+			f.PC++
+		}
+	}
 }
-
-// padSigned pads a signed value with the specified number of bits to a two's complement representation.
-// func padSigned(value []byte, bits int) []byte {
-// 	// Calculate the required padding
-// 	padding := (bits - len(value)*8) % 8
-
-// 	// If the value is negative and there is padding, add the padding to the value
-// 	if value[0]&0x80 != 0 && padding > 0 {
-// 		value = append([]byte{0xff}, value...)
-// 	}
-
-// 	// Return the padded value
-// 	return value
-// }
-
-// func SignExtEnd(code []byte, stack []*big.Int) []*big.Int {
-// 	// Get the second and third items from the stack.
-// 	// These are the position and value to sign extend, respectively.
-// 	pos := stack[len(stack)-2]
-// 	val := stack[len(stack)-1]
-
-// 	// Check if the position is within the bounds of the value.
-// 	if pos.Cmp(big.NewInt(31)) < 0 && pos.Cmp(big.NewInt(0)) >= 0 {
-// 		// Create a new big.Int to store the result.
-// 		res := big.NewInt(0)
-
-// 		// Convert the value to two's complement representation.
-// 		val.Neg(val)
-// 		val.Add(val, big.NewInt(1))
-
-// 		// Check if the value at the specified position is equal to 1 or 0.
-// 		// If it is 1, the value should be sign extended for positive numbers.
-// 		// If it is 0, the value should be sign extended for negative numbers.
-// 		if val.Bit(int(pos.Int64())) == 1 || val.Bit(int(pos.Int64())) == 0 {
-// 			// Create a mask with all bits set to 1.
-// 			mask := big.NewInt(0)
-// 			mask.SetBit(mask, 256, 1)
-
-// 			// Convert the result of the subtraction to uint before passing it to the Rsh function.
-// 			mask.Rsh(mask, uint(255-pos.Int64()))
-
-// 			// Perform a bitwise OR operation between the value and the mask.
-// 			// This will set all bits after the specified position to 1 for positive numbers,
-// 			// or all bits before the specified position to 1 for negative numbers.
-// 			res.Or(val, mask)
-
-// 			// Convert the value back to its original representation.
-// 			res.Neg(res)
-// 			res.Add(res, big.NewInt(1))
-// 		} else {
-// 			// If the value at the specified position is not 1 or 0,
-// 			// then no sign extension is needed, so we can just return the value.
-// 			res = val
-// 		}
-
-// 		// Pop the last two items from the stack and append the result to the stack.
-// 		return append(stack[:len(stack)-2], res)
-// 	} else {
-// 		// If the position is not within the bounds of the value,
-// 		// we return the original stack without modifying it.
-// 		return stack
-// 	}
-// }
-
-// SignExtend converts the negative value at the specified byte offset to a two's complement representation.
-// func SignExtend(code []byte, stack []*big.Int) []*big.Int {
-// 	// Get the byte offset and bit offset from the code
-// 	offset := new(big.Int).SetBytes(code[1:])
-// 	bitoffset := code[0]
-
-// 	// Get the value at the specified offset on the stack
-// 	value := stack[offset.Int64()]
-
-// 	// Convert the value to a two's complement representation with the specified number of bits
-// 	result := new(big.Int).SetBytes(padSigned(value.Bytes(), int(bitoffset)))
-
-// 	// Return the result on the stack
-// 	return append(stack, result)
-// }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"math/big"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -12,10 +13,27 @@ import (
 )
 
 type testCase struct {
-	Name string `json:"name"`
-	Hint string `json:"hint"`
-	Code code   `json:"code"`
-	Want want   `json:"expect"`
+	Name  string                   `json:"name"`
+	Hint  string                   `json:"hint"`
+	Code  code                     `json:"code"`
+	Tx    tx                       `json:"tx"`
+	Block block                    `json:"block"`
+	State map[Address]stateAccount `json:"state"`
+	Want  want                     `json:"expect"`
+}
+
+type tx struct {
+	To       Address    `json:"to"`
+	From     Address    `json:"from"`
+	Origin   Address    `json:"origin"`
+	GasPrice *hexBigInt `json:"gasprice"`
+	Value    *hexBigInt `json:"value"`
+	Data     string     `json:"data"`
+}
+
+type stateAccount struct {
+	Balance *hexBigInt `json:"balance"`
+	Code    code       `json:"code"`
 }
 
 type code struct {
@@ -27,6 +45,55 @@ type want struct {
 	Stack   []hexBigInt `json:"stack"`
 	Success bool        `json:"success"`
 	Return  string      `json:"return"`
+	Logs    []testLog   `json:"logs"`
+}
+
+type testLog struct {
+	Address Address     `json:"address"`
+	Data    string      `json:"data"`
+	Topics  []hexBigInt `json:"topics"`
+}
+
+// A logStrings is a log in a form that compares equal to the evm.json
+// expectation.
+type logStrings struct {
+	Address string
+	Data    string
+	Topics  []string
+}
+
+func (w *want) logStrings() []logStrings {
+	out := make([]logStrings, len(w.Logs))
+	for i, l := range w.Logs {
+		topics := make([]*big.Int, len(l.Topics))
+		for j := range l.Topics {
+			topics[j] = l.Topics[j].Int
+		}
+		out[i] = logStrings{l.Address.Hex(), strings.ToLower(l.Data), toHexStrings(topics)}
+	}
+	return out
+}
+
+func toLogStrings(logs []*Log) []logStrings {
+	out := make([]logStrings, len(logs))
+	for i, l := range logs {
+		topics := make([]*big.Int, len(l.Topics))
+		for j := range l.Topics {
+			topics[j] = l.Topics[j].ToBig()
+		}
+		out[i] = logStrings{l.Address.Hex(), hex.EncodeToString(l.Data), toHexStrings(topics)}
+	}
+	return out
+}
+
+type block struct {
+	Coinbase   Address    `json:"coinbase"`
+	Timestamp  *hexBigInt `json:"timestamp"`
+	Number     *hexBigInt `json:"number"`
+	Difficulty *hexBigInt `json:"difficulty"`
+	GasLimit   *hexBigInt `json:"gaslimit"`
+	ChainID    *hexBigInt `json:"chainid"`
+	BaseFee    *hexBigInt `json:"basefee"`
 }
 
 // A hexBigInt is a *big.Int that can be read from a JSON hex string.
@@ -47,6 +114,66 @@ func (i *hexBigInt) UnmarshalJSON(b []byte) error {
 	return i.Int.UnmarshalJSON([]byte(s))
 }
 
+// newState returns a MemoryStateDB holding the accounts in tt.State.
+func (tt *testCase) newState(t *testing.T) *MemoryStateDB {
+	db := NewMemoryStateDB()
+	for addr, acc := range tt.State {
+		db.CreateAccount(addr)
+		if acc.Balance != nil {
+			db.SetBalance(addr, WordFromBig(acc.Balance.Int))
+		}
+		code, err := hex.DecodeString(acc.Code.Bin)
+		if err != nil {
+			fatalAndBugReport(t, "hex.DecodeString(%q) error %v", acc.Code.Bin, err)
+		}
+		db.SetCode(addr, code)
+	}
+	return db
+}
+
+// context returns the ExecutionContext that runs tt.
+func (tt *testCase) context(t *testing.T, code []byte) *ExecutionContext {
+	input, err := hex.DecodeString(tt.Tx.Data)
+	if err != nil {
+		fatalAndBugReport(t, "hex.DecodeString(%q) error %v", tt.Tx.Data, err)
+	}
+	ctx := &ExecutionContext{Code: code}
+	ctx.Input = input
+	ctx.Address = tt.Tx.To
+	ctx.Caller = tt.Tx.From
+	ctx.Origin = tt.Tx.Origin
+	if tt.Tx.Value != nil {
+		ctx.Value = *WordFromBig(tt.Tx.Value.Int)
+	}
+	if tt.Tx.GasPrice != nil {
+		ctx.GasPrice = *WordFromBig(tt.Tx.GasPrice.Int)
+	}
+
+	ctx.Coinbase = tt.Block.Coinbase
+	if tt.Block.Timestamp != nil {
+		ctx.Timestamp = tt.Block.Timestamp.Uint64()
+	}
+	if tt.Block.Number != nil {
+		ctx.Number = tt.Block.Number.Uint64()
+	}
+	if tt.Block.Difficulty != nil {
+		// The tests predate the Merge, so the value is what DIFFICULTY
+		// returns under any fork.
+		ctx.Difficulty = *WordFromBig(tt.Block.Difficulty.Int)
+		ctx.Random = ctx.Difficulty
+	}
+	if tt.Block.GasLimit != nil {
+		ctx.GasLimit = tt.Block.GasLimit.Uint64()
+	}
+	if tt.Block.ChainID != nil {
+		ctx.ChainID = *WordFromBig(tt.Block.ChainID.Int)
+	}
+	if tt.Block.BaseFee != nil {
+		ctx.BaseFee = *WordFromBig(tt.Block.BaseFee.Int)
+	}
+	return ctx
+}
+
 // StackInts returns the underlying *big.Int values of w.Stack, unwrapping them
 // from within the JSON-unmarshalling helper.
 func (w *want) StackInts() []*big.Int {
@@ -92,12 +219,23 @@ func TestEVM(t *testing.T) {
 				fatalAndBugReport(t, "hex.DecodeString(%q) error %v", tt.Code.Bin, err)
 			}
 
-			got, gotSuccess := Evm(bin)
-			if gotSuccess != tt.Want.Success {
-				t.Errorf("Evm(…) got success = %t; want %t", gotSuccess, tt.Want.Success)
+			in := NewInterpreter(WithUnlimitedGas(), WithoutBalanceCheck(), WithImmediateSelfDestruct(), WithStateDB(tt.newState(t)))
+			res := in.Run(tt.context(t, bin))
+			if gotSuccess := !res.Failed(); gotSuccess != tt.Want.Success {
+				t.Errorf("Run(…) got success = %t; want %t (err = %v)", gotSuccess, tt.Want.Success, res.Err)
+			}
+			got := make([]*big.Int, len(res.Stack))
+			for i := range res.Stack {
+				got[i] = res.Stack[i].ToBig()
 			}
 			if diff := cmp.Diff(toHexStrings(tt.Want.StackInts()), toHexStrings(got), cmpopts.EquateEmpty()); diff != "" {
-				t.Errorf("Evm(…) stack mismatch; diff (-want +got)\n%s", diff)
+				t.Errorf("Run(…) stack mismatch; diff (-want +got)\n%s", diff)
+			}
+			if got, want := hex.EncodeToString(res.ReturnData), strings.ToLower(tt.Want.Return); got != want {
+				t.Errorf("Run(…) got return = %q; want %q", got, want)
+			}
+			if diff := cmp.Diff(tt.Want.logStrings(), toLogStrings(res.Logs), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Run(…) logs mismatch; diff (-want +got)\n%s", diff)
 			}
 
 			if t.Failed() {
@@ -118,6 +256,37 @@ func TestEVM(t *testing.T) {
 	}
 }
 
+func TestEvmWrapper(t *testing.T) {
+	tests := []struct {
+		code    string
+		stack   []string
+		success bool
+	}{
+		{"6001600201", []string{"0x3"}, true}, // PUSH1 1, PUSH1 2, ADD
+		{"6001fe", nil, false},                // PUSH1 1, INVALID
+		// PUSH1 1, PUSH1 0, SSTORE, then PUSH1 0, SLOAD: every call starts
+		// with an empty state.
+		{"6001600055", nil, true},
+		{"600054", []string{"0x0"}, true},
+	}
+	for _, tt := range tests {
+		code, err := hex.DecodeString(tt.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stack, success := Evm(code)
+		if success != tt.success {
+			t.Errorf("Evm(%s) success = %t; want %t", tt.code, success, tt.success)
+		}
+		if !success && stack != nil {
+			t.Errorf("Evm(%s) stack = %v; want nil", tt.code, stack)
+		}
+		if diff := cmp.Diff(tt.stack, toHexStrings(stack), cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("Evm(%s) stack mismatch; diff (-want +got)\n%s", tt.code, diff)
+		}
+	}
+}
+
 // fatalAndBugReport calls t.Errorf(format, a...) and then t.Fatal() with a
 // message requesting that the student files a bug report. It's intended use is
 // as a replacement for t.Fatal() when the error is in the test setup, not in
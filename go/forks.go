@@ -0,0 +1,92 @@
+package evm
+
+// A Fork identifies an Ethereum hard fork. Forks are ordered, so a later
+// fork compares greater than every fork before it.
+type Fork int
+
+const (
+	Frontier Fork = iota
+	Homestead
+	TangerineWhistle // EIP-150
+	SpuriousDragon   // EIP-155, EIP-158
+	Byzantium
+	Constantinople
+	Petersburg
+	Istanbul
+	Berlin
+	London
+	Merge // Paris
+	Shanghai
+	Cancun
+
+	// LatestFork is the newest fork the interpreter implements and the
+	// default for NewInterpreter.
+	LatestFork = Cancun
+)
+
+var forkNames = [...]string{
+	Frontier:         "Frontier",
+	Homestead:        "Homestead",
+	TangerineWhistle: "TangerineWhistle",
+	SpuriousDragon:   "SpuriousDragon",
+	Byzantium:        "Byzantium",
+	Constantinople:   "Constantinople",
+	Petersburg:       "Petersburg",
+	Istanbul:         "Istanbul",
+	Berlin:           "Berlin",
+	London:           "London",
+	Merge:            "Merge",
+	Shanghai:         "Shanghai",
+	Cancun:           "Cancun",
+}
+
+func (f Fork) String() string {
+	if f < 0 || int(f) >= len(forkNames) {
+		return "Fork(unknown)"
+	}
+	return forkNames[f]
+}
+
+// Supported reports whether the interpreter implements f, that is whether f
+// lies between Frontier and LatestFork.
+func (f Fork) Supported() bool {
+	return Frontier <= f && f <= LatestFork
+}
+
+// ChainRules records which protocol changes are active under a fork. Each
+// flag is true for its fork and every fork after it.
+type ChainRules struct {
+	Fork Fork
+
+	IsHomestead      bool
+	IsEIP150         bool // TangerineWhistle gas repricing
+	IsEIP158         bool // SpuriousDragon state clearing
+	IsByzantium      bool
+	IsConstantinople bool
+	IsPetersburg     bool
+	IsIstanbul       bool
+	IsBerlin         bool
+	IsLondon         bool
+	IsMerge          bool
+	IsShanghai       bool
+	IsCancun         bool
+}
+
+// Rules returns the ChainRules in effect under f.
+func (f Fork) Rules() ChainRules {
+	return ChainRules{
+		Fork:             f,
+		IsHomestead:      f >= Homestead,
+		IsEIP150:         f >= TangerineWhistle,
+		IsEIP158:         f >= SpuriousDragon,
+		IsByzantium:      f >= Byzantium,
+		IsConstantinople: f >= Constantinople,
+		IsPetersburg:     f >= Petersburg,
+		IsIstanbul:       f >= Istanbul,
+		IsBerlin:         f >= Berlin,
+		IsLondon:         f >= London,
+		IsMerge:          f >= Merge,
+		IsShanghai:       f >= Shanghai,
+		IsCancun:         f >= Cancun,
+	}
+}
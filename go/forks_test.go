@@ -0,0 +1,101 @@
+package evm
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestForkRules(t *testing.T) {
+	tests := []struct {
+		name string
+		fork Fork
+		code string
+		err  error
+		top  uint64 // expected top of stack on success
+		gas  uint64 // expected gas used on success, if not zero
+	}{
+		// PUSH1 1, PUSH1 1, SHL
+		{"SHL/Byzantium", Byzantium, "600160011b", ErrInvalidOpcode, 0, 0},
+		{"SHL/Constantinople", Constantinople, "600160011b", nil, 2, 0},
+		{"PUSH0/Merge", Merge, "5f", ErrInvalidOpcode, 0, 0},
+		{"PUSH0/Shanghai", Shanghai, "5f", nil, 0, 2},
+		{"BASEFEE/Berlin", Berlin, "48", ErrInvalidOpcode, 0, 0},
+		{"BASEFEE/London", London, "48", nil, 7, 2},
+		{"DIFFICULTY/London", London, "44", nil, 5, 0},
+		{"PREVRANDAO/Merge", Merge, "44", nil, 9, 0},
+		// PUSH1 0, TLOAD
+		{"TLOAD/Shanghai", Shanghai, "60005c", ErrInvalidOpcode, 0, 0},
+		{"TLOAD/Cancun", Cancun, "60005c", nil, 0, 103},
+		// PUSH2 0x100, PUSH1 2, EXP: a two-byte exponent.
+		{"EXP/Frontier", Frontier, "61010060020a", nil, 0, 36},
+		{"EXP/TangerineWhistle", TangerineWhistle, "61010060020a", nil, 0, 36},
+		{"EXP/SpuriousDragon", SpuriousDragon, "61010060020a", nil, 0, 116},
+		// COINBASE, BALANCE: the coinbase is warm from Shanghai (EIP-3651).
+		{"coinbase/London", London, "4131", nil, 0, 2602},
+		{"coinbase/Shanghai", Shanghai, "4131", nil, 0, 102},
+	}
+	for _, tt := range tests {
+		code, err := hex.DecodeString(tt.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := &ExecutionContext{Code: code, Gas: 100000}
+		ctx.Coinbase = Address{0xc0}
+		ctx.BaseFee = *NewWord(7)
+		ctx.Difficulty = *NewWord(5)
+		ctx.Random = *NewWord(9)
+
+		res := NewInterpreter(WithFork(tt.fork)).Run(ctx)
+		if !errors.Is(res.Err, tt.err) {
+			t.Errorf("%s: Err = %v; want %v", tt.name, res.Err, tt.err)
+			continue
+		}
+		if tt.err != nil {
+			continue
+		}
+		if top := res.Stack[0]; top != *NewWord(tt.top) {
+			t.Errorf("%s: top of stack = %v; want %d", tt.name, &top, tt.top)
+		}
+		if tt.gas != 0 && res.GasUsed != tt.gas {
+			t.Errorf("%s: used %d gas; want %d", tt.name, res.GasUsed, tt.gas)
+		}
+	}
+}
+
+func TestContextFork(t *testing.T) {
+	code := []byte{0x5f} // PUSH0, from Shanghai
+	in := NewInterpreter(WithFork(Cancun))
+	merge, cancun := Merge, Cancun
+	tests := []struct {
+		name string
+		fork *Fork
+		err  error
+	}{
+		{"default", nil, nil},
+		{"Merge", &merge, ErrInvalidOpcode},
+		{"Cancun", &cancun, nil},
+	}
+	for _, tt := range tests {
+		res := in.Run(&ExecutionContext{Code: code, Gas: 100, Fork: tt.fork})
+		if !errors.Is(res.Err, tt.err) {
+			t.Errorf("%s: Err = %v; want %v", tt.name, res.Err, tt.err)
+		}
+	}
+	if got := in.Rules().Fork; got != Cancun {
+		t.Errorf("Rules().Fork = %v after overriding it; want %v", got, Cancun)
+	}
+}
+
+func TestUnsupportedFork(t *testing.T) {
+	code := []byte{0x00}
+	res := NewInterpreter(WithFork(Fork(99))).Run(&ExecutionContext{Code: code})
+	if res.Err != ErrUnsupportedFork {
+		t.Errorf("WithFork(99): Err = %v; want %v", res.Err, ErrUnsupportedFork)
+	}
+	bad := Fork(-1)
+	res = NewInterpreter().Run(&ExecutionContext{Code: code, Fork: &bad})
+	if res.Err != ErrUnsupportedFork {
+		t.Errorf("ExecutionContext.Fork -1: Err = %v; want %v", res.Err, ErrUnsupportedFork)
+	}
+}
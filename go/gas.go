@@ -0,0 +1,497 @@
+package evm
+
+import (
+	"errors"
+	"math/bits"
+)
+
+var (
+	// ErrOutOfGas is returned when an instruction costs more gas than
+	// remains.
+	ErrOutOfGas = errors.New("out of gas")
+	// ErrGasUintOverflow is returned when a gas or memory size calculation
+	// does not fit in 64 bits. No gas budget could ever pay for it.
+	ErrGasUintOverflow = errors.New("gas uint64 overflow")
+)
+
+// Static gas costs shared by groups of instructions, named after the tiers in
+// appendix G of the Yellow Paper.
+const (
+	GasZero        uint64 = 0
+	GasJumpDest    uint64 = 1
+	GasQuickStep   uint64 = 2
+	GasFastestStep uint64 = 3
+	GasFastStep    uint64 = 5
+	GasMidStep     uint64 = 8
+	GasSlowStep    uint64 = 10
+	GasExtStep     uint64 = 20
+)
+
+// BlockHashGas is the static cost of BLOCKHASH.
+const BlockHashGas uint64 = 20
+
+// Costs and limits of CREATE and CREATE2.
+const (
+	CreateGas       uint64 = 32000           // static cost of CREATE and CREATE2
+	CreateDataGas   uint64 = 200             // per byte of code deposited
+	InitCodeWordGas uint64 = 2               // per word of init code from Shanghai (EIP-3860)
+	MaxCodeSize            = 24576           // longest deployable code from SpuriousDragon (EIP-170)
+	MaxInitCodeSize        = 2 * MaxCodeSize // longest init code from Shanghai (EIP-3860)
+)
+
+// Costs of SELFDESTRUCT.
+const (
+	SelfdestructGasEIP150   uint64 = 5000  // static cost from TangerineWhistle
+	CreateBySelfdestructGas uint64 = 25000 // when the beneficiary is created
+	SelfdestructRefundGas   uint64 = 24000 // refunded before London
+)
+
+// Costs of the CALL family.
+const (
+	CallGasFrontier      uint64 = 40    // static cost before TangerineWhistle
+	CallGasEIP150        uint64 = 700   // static cost from TangerineWhistle
+	CallValueTransferGas uint64 = 9000  // for a call that transfers value
+	CallNewAccountGas    uint64 = 25000 // for a call that creates an account
+	CallStipend          uint64 = 2300  // free gas given to a call that transfers value
+)
+
+// Parameters of the dynamic gas formulas.
+const (
+	MemoryGas          uint64 = 3   // per word of memory
+	QuadCoeffDiv       uint64 = 512 // divisor of the quadratic memory term
+	ExpByteGasFrontier uint64 = 10  // per byte of EXP exponent
+	ExpByteGasEIP158   uint64 = 50  // per byte of EXP exponent from SpuriousDragon
+	Sha3Gas            uint64 = 30  // static cost of SHA3
+	Sha3WordGas        uint64 = 6   // per word hashed by SHA3
+	CopyGas            uint64 = 3   // per word copied by the *COPY instructions
+	LogGas             uint64 = 375 // static cost of LOGn
+	LogTopicGas        uint64 = 375 // per LOG topic
+	LogDataGas         uint64 = 8   // per byte of LOG data
+)
+
+// Costs of accessing accounts in the state, which were repriced several
+// times and, from Berlin, depend on whether the account was accessed before.
+const (
+	BalanceGasFrontier    uint64 = 20   // BALANCE before TangerineWhistle
+	BalanceGasEIP150      uint64 = 400  // BALANCE from TangerineWhistle
+	BalanceGasEIP1884     uint64 = 700  // BALANCE from Istanbul
+	ExtcodeSizeGas        uint64 = 20   // EXTCODESIZE and EXTCODECOPY before TangerineWhistle
+	ExtcodeSizeGasEIP150  uint64 = 700  // EXTCODESIZE and EXTCODECOPY from TangerineWhistle
+	ExtcodeHashGas        uint64 = 400  // EXTCODEHASH in Constantinople
+	ExtcodeHashGasEIP1884 uint64 = 700  // EXTCODEHASH from Istanbul
+	WarmStorageReadCost   uint64 = 100  // any access to a warm account or slot
+	ColdAccountAccessCost uint64 = 2600 // first access to an account
+	ColdSloadCost         uint64 = 2100 // first access to a storage slot
+
+	SloadGasFrontier uint64 = 50  // SLOAD before TangerineWhistle
+	SloadGasEIP150   uint64 = 200 // SLOAD from TangerineWhistle
+	SloadGasEIP1884  uint64 = 800 // SLOAD from Istanbul
+
+	SstoreSetGas     uint64 = 20000 // SSTORE of a zero slot to non-zero
+	SstoreResetGas   uint64 = 5000  // any other SSTORE that changes the slot
+	SstoreSentryGas  uint64 = 2300  // SSTORE fails unless more gas than this remains (EIP-2200)
+	NetSstoreNoopGas uint64 = 200   // SSTORE of an unchanged or dirty slot under EIP-1283
+
+	SstoreClearsScheduleRefund        uint64 = 15000 // refund for clearing a slot
+	SstoreClearsScheduleRefundEIP3529 uint64 = 4800  // refund for clearing a slot from London
+
+	RefundQuotient        uint64 = 2 // at most 1/2 of the gas used is refunded
+	RefundQuotientEIP3529 uint64 = 5 // at most 1/5 from London
+)
+
+// maxMemorySize is the largest memory size whose expansion cost still fits
+// in a uint64.
+const maxMemorySize = 0x1FFFFFFFE0
+
+// unlimitedMemorySize caps memory under WithUnlimitedGas, whose budget would
+// otherwise pay for memories of up to maxMemorySize bytes. Expanding memory
+// to 32 MiB already costs over 2e9 gas.
+const unlimitedMemorySize = 32 << 20
+
+// A gasFunc returns the dynamic part of an instruction's gas cost. memorySize
+// is the word-aligned memory size the instruction needs, or 0 if it does not
+// touch memory; the gasFunc must include the cost of expanding to it.
+type gasFunc func(f *Frame, memorySize uint64) (uint64, error)
+
+// useGas deducts amount from the frame's remaining gas, or returns
+// ErrOutOfGas if not enough is left.
+func (f *Frame) useGas(amount uint64) error {
+	if f.Gas < amount {
+		return ErrOutOfGas
+	}
+	f.Gas -= amount
+	return nil
+}
+
+// safeAdd returns a+b and whether the addition overflowed.
+func safeAdd(a, b uint64) (uint64, bool) {
+	sum, carry := bits.Add64(a, b, 0)
+	return sum, carry != 0
+}
+
+// safeMul returns a*b and whether the multiplication overflowed.
+func safeMul(a, b uint64) (uint64, bool) {
+	hi, lo := bits.Mul64(a, b)
+	return lo, hi != 0
+}
+
+// memoryGasCost returns the gas needed to expand mem to newSize bytes. The
+// total cost of a memory of w words is 3w + w²/512, and only the difference
+// from what has already been paid is charged.
+func memoryGasCost(mem *Memory, newSize uint64) (uint64, error) {
+	if newSize == 0 {
+		return 0, nil
+	}
+	if newSize > maxMemorySize {
+		return 0, ErrGasUintOverflow
+	}
+	if newSize <= uint64(mem.Len()) {
+		return 0, nil
+	}
+	words := toWordSize(newSize)
+	total := words*MemoryGas + words*words/QuadCoeffDiv
+	fee := total - mem.lastGasCost
+	mem.lastGasCost = total
+	return fee, nil
+}
+
+// gasMemory charges for memory expansion only, for instructions whose
+// remaining cost is static.
+func gasMemory(f *Frame, memorySize uint64) (uint64, error) {
+	return memoryGasCost(f.Memory, memorySize)
+}
+
+// makeGasExp returns the gasFunc of EXP, which charges byteGas for every byte
+// of the exponent.
+func makeGasExp(byteGas uint64) gasFunc {
+	return func(f *Frame, memorySize uint64) (uint64, error) {
+		expBytes := uint64((f.Stack.back(1).BitLen() + 7) / 8)
+		return expBytes * byteGas, nil
+	}
+}
+
+// gasWords returns memory expansion plus perWord gas for every 32-byte word
+// of size, the shape shared by SHA3 and the *COPY instructions.
+func gasWords(f *Frame, memorySize uint64, size *Word, perWord uint64) (uint64, error) {
+	gas, err := memoryGasCost(f.Memory, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	if !size.IsUint64() {
+		return 0, ErrGasUintOverflow
+	}
+	wordGas, overflow := safeMul(toWordSize(size[0]), perWord)
+	if overflow {
+		return 0, ErrGasUintOverflow
+	}
+	if gas, overflow = safeAdd(gas, wordGas); overflow {
+		return 0, ErrGasUintOverflow
+	}
+	return gas, nil
+}
+
+// gasSha3 charges memory expansion plus Sha3WordGas per word hashed.
+func gasSha3(f *Frame, memorySize uint64) (uint64, error) {
+	return gasWords(f, memorySize, f.Stack.back(1), Sha3WordGas)
+}
+
+// memoryCopierGas returns the gasFunc of a *COPY instruction whose byte count
+// is stack item stackpos, counting from the top.
+func memoryCopierGas(stackpos int) gasFunc {
+	return func(f *Frame, memorySize uint64) (uint64, error) {
+		return gasWords(f, memorySize, f.Stack.back(stackpos), CopyGas)
+	}
+}
+
+// makeGasLog returns the gasFunc of LOGn: memory expansion, LogTopicGas per
+// topic and LogDataGas per byte of data.
+func makeGasLog(n uint64) gasFunc {
+	return func(f *Frame, memorySize uint64) (uint64, error) {
+		size := f.Stack.back(1)
+		if !size.IsUint64() {
+			return 0, ErrGasUintOverflow
+		}
+		gas, err := memoryGasCost(f.Memory, memorySize)
+		if err != nil {
+			return 0, err
+		}
+		var overflow bool
+		if gas, overflow = safeAdd(gas, LogGas+n*LogTopicGas); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		dataGas, overflow := safeMul(size[0], LogDataGas)
+		if overflow {
+			return 0, ErrGasUintOverflow
+		}
+		if gas, overflow = safeAdd(gas, dataGas); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		return gas, nil
+	}
+}
+
+// accountAccessGasEIP2929 returns the EIP-2929 surcharge for the first access
+// to addr in a transaction and warms addr. The warm cost is part of the
+// instruction's constant gas.
+func accountAccessGasEIP2929(f *Frame, addr Address) uint64 {
+	if f.in.state.AddressInAccessList(addr) {
+		return 0
+	}
+	f.in.state.AddAddressToAccessList(addr)
+	return ColdAccountAccessCost - WarmStorageReadCost
+}
+
+// gasAccountAccessEIP2929 is the dynamic gas of instructions that access the
+// account on top of the stack.
+func gasAccountAccessEIP2929(f *Frame, memorySize uint64) (uint64, error) {
+	return accountAccessGasEIP2929(f, f.Stack.peek().Bytes20()), nil
+}
+
+// gasExtCodeCopyEIP2929 is the dynamic gas of EXTCODECOPY from Berlin.
+func gasExtCodeCopyEIP2929(f *Frame, memorySize uint64) (uint64, error) {
+	gas, err := memoryCopierGas(3)(f, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	gas, overflow := safeAdd(gas, accountAccessGasEIP2929(f, f.Stack.peek().Bytes20()))
+	if overflow {
+		return 0, ErrGasUintOverflow
+	}
+	return gas, nil
+}
+
+// gasSLoadEIP2929 charges SLOAD by whether its slot is warm, and warms it.
+func gasSLoadEIP2929(f *Frame, memorySize uint64) (uint64, error) {
+	slot := f.Stack.peek()
+	if _, ok := f.in.state.SlotInAccessList(f.Address, slot); ok {
+		return WarmStorageReadCost, nil
+	}
+	f.in.state.AddSlotToAccessList(f.Address, slot)
+	return ColdSloadCost, nil
+}
+
+// gasSStoreLegacy charges SSTORE by the slot's current value alone, as
+// before net gas metering.
+func gasSStoreLegacy(f *Frame, memorySize uint64) (uint64, error) {
+	current := f.in.state.GetState(f.Address, f.Stack.back(0))
+	value := f.Stack.back(1)
+	switch {
+	case current.IsZero() && !value.IsZero():
+		return SstoreSetGas, nil
+	case !current.IsZero() && value.IsZero():
+		f.in.state.AddRefund(SstoreClearsScheduleRefund)
+		return SstoreResetGas, nil
+	default:
+		return SstoreResetGas, nil
+	}
+}
+
+// sstoreGas parameterizes net gas metering for SSTORE, which charges a write
+// by comparing the slot's new value with its current value and with its
+// value at the start of the transaction.
+type sstoreGas struct {
+	sentry      bool   // enforce SstoreSentryGas (EIP-2200)
+	coldSload   uint64 // surcharge for a cold slot, 0 before Berlin (EIP-2929)
+	noop        uint64 // cost of a write that changes nothing or an already dirty slot
+	clearRefund uint64 // refund for clearing a slot
+}
+
+// makeGasSStore returns the gasFunc for SSTORE under net gas metering as
+// specified by EIP-1283, EIP-2200, EIP-2929 and EIP-3529, depending on p.
+func makeGasSStore(p sstoreGas) gasFunc {
+	reset := SstoreResetGas - p.coldSload
+	return func(f *Frame, memorySize uint64) (uint64, error) {
+		if p.sentry && f.Gas <= SstoreSentryGas {
+			return 0, ErrOutOfGas
+		}
+		state, slot := f.in.state, f.Stack.back(0)
+		var cost uint64
+		if p.coldSload > 0 {
+			if _, ok := state.SlotInAccessList(f.Address, slot); !ok {
+				state.AddSlotToAccessList(f.Address, slot)
+				cost = p.coldSload
+			}
+		}
+
+		current := state.GetState(f.Address, slot)
+		value := f.Stack.back(1)
+		if current.Eq(value) {
+			return cost + p.noop, nil
+		}
+		original := state.GetCommittedState(f.Address, slot)
+		if original.Eq(&current) {
+			if original.IsZero() {
+				return cost + SstoreSetGas, nil
+			}
+			if value.IsZero() {
+				state.AddRefund(p.clearRefund)
+			}
+			return cost + reset, nil
+		}
+
+		// The slot is dirty: it was already written in this transaction, so
+		// the write is cheap, but refunds granted or withheld for earlier
+		// writes must be corrected.
+		if !original.IsZero() {
+			if current.IsZero() {
+				state.SubRefund(p.clearRefund)
+			} else if value.IsZero() {
+				state.AddRefund(p.clearRefund)
+			}
+		}
+		if original.Eq(value) {
+			if original.IsZero() {
+				state.AddRefund(SstoreSetGas - p.noop)
+			} else {
+				state.AddRefund(reset - p.noop)
+			}
+		}
+		return cost + p.noop, nil
+	}
+}
+
+// callGas returns the gas a call forwards when it asks for requested and
+// available remains after base, its other costs, have been paid. From
+// TangerineWhistle a call can forward at most all but one 64th of it
+// (EIP-150); before, it must be given exactly what it asks for.
+func callGas(isEIP150 bool, available, base uint64, requested *Word) (uint64, error) {
+	if isEIP150 {
+		if base > available {
+			return 0, ErrOutOfGas
+		}
+		available -= base
+		gas := available - available/64
+		if !requested.IsUint64() || gas < requested[0] {
+			return gas, nil
+		}
+	}
+	if !requested.IsUint64() {
+		return 0, ErrGasUintOverflow
+	}
+	return requested[0], nil
+}
+
+// makeGasCall returns the gasFunc of a CALL-type instruction. base returns
+// the instruction's costs other than memory and forwarded gas. The forwarded
+// gas is left in f.callGasTemp for the instruction to use.
+func makeGasCall(base func(f *Frame) uint64) gasFunc {
+	return func(f *Frame, memorySize uint64) (uint64, error) {
+		gas, err := memoryGasCost(f.Memory, memorySize)
+		if err != nil {
+			return 0, err
+		}
+		var overflow bool
+		if gas, overflow = safeAdd(gas, base(f)); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		if f.in.unlimitedGas {
+			// Forward all the gas there is, whatever the call asks for.
+			f.callGasTemp, err = callGas(true, f.Gas, gas, new(Word).SetAllOne())
+		} else {
+			f.callGasTemp, err = callGas(f.in.rules.IsEIP150, f.Gas, gas, f.Stack.back(0))
+		}
+		if err != nil {
+			return 0, err
+		}
+		if gas, overflow = safeAdd(gas, f.callGasTemp); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		return gas, nil
+	}
+}
+
+var (
+	gasCall = makeGasCall(func(f *Frame) uint64 {
+		var gas uint64
+		addr, transfersValue := Address(f.Stack.back(1).Bytes20()), !f.Stack.back(2).IsZero()
+		if f.in.rules.IsEIP158 {
+			if transfersValue && f.in.state.Empty(addr) {
+				gas += CallNewAccountGas
+			}
+		} else if !f.in.state.Exist(addr) {
+			gas += CallNewAccountGas
+		}
+		if transfersValue {
+			gas += CallValueTransferGas
+		}
+		return gas
+	})
+	gasCallCode = makeGasCall(func(f *Frame) uint64 {
+		if !f.Stack.back(2).IsZero() {
+			return CallValueTransferGas
+		}
+		return 0
+	})
+	gasDelegateCall = makeGasCall(func(f *Frame) uint64 { return 0 })
+	gasStaticCall   = gasDelegateCall
+)
+
+// makeGasCallEIP2929 adds the EIP-2929 surcharge for a cold callee to the
+// gasFunc of a CALL-type instruction. The surcharge is paid before the
+// forwarded gas is worked out, so it cannot be forwarded.
+func makeGasCallEIP2929(gasCall gasFunc) gasFunc {
+	return func(f *Frame, memorySize uint64) (uint64, error) {
+		coldCost := accountAccessGasEIP2929(f, f.Stack.back(1).Bytes20())
+		if err := f.useGas(coldCost); err != nil {
+			return 0, err
+		}
+		gas, err := gasCall(f, memorySize)
+		// The interpreter charges the returned total, so give the
+		// surcharge back rather than take it twice.
+		f.Gas += coldCost
+		if err != nil {
+			return 0, err
+		}
+		return gas + coldCost, nil
+	}
+}
+
+// gasCreate2 charges memory expansion plus hashing the init code, which
+// CREATE2 needs to derive the address.
+func gasCreate2(f *Frame, memorySize uint64) (uint64, error) {
+	return gasWords(f, memorySize, f.Stack.back(2), Sha3WordGas)
+}
+
+// gasCreateEIP3860 charges CREATE for memory expansion and metering the init
+// code, which must not exceed MaxInitCodeSize.
+func gasCreateEIP3860(f *Frame, memorySize uint64) (uint64, error) {
+	size := f.Stack.back(2)
+	if !size.IsUint64() || size[0] > MaxInitCodeSize {
+		return 0, ErrMaxInitCodeSizeExceeded
+	}
+	return gasWords(f, memorySize, size, InitCodeWordGas)
+}
+
+// gasCreate2EIP3860 is gasCreateEIP3860 for CREATE2, which also hashes the
+// init code.
+func gasCreate2EIP3860(f *Frame, memorySize uint64) (uint64, error) {
+	size := f.Stack.back(2)
+	if !size.IsUint64() || size[0] > MaxInitCodeSize {
+		return 0, ErrMaxInitCodeSizeExceeded
+	}
+	return gasWords(f, memorySize, size, Sha3WordGas+InitCodeWordGas)
+}
+
+// gasSelfdestruct is the dynamic gas of SELFDESTRUCT, which pays for creating
+// the beneficiary and, from Berlin, for accessing it cold.
+func gasSelfdestruct(f *Frame, memorySize uint64) (uint64, error) {
+	var gas uint64
+	db, rules := f.in.state, f.in.rules
+	beneficiary := Address(f.Stack.peek().Bytes20())
+	if rules.IsBerlin && !db.AddressInAccessList(beneficiary) {
+		db.AddAddressToAccessList(beneficiary)
+		gas = ColdAccountAccessCost
+	}
+	if rules.IsEIP158 {
+		balance := db.GetBalance(f.Address)
+		if db.Empty(beneficiary) && !balance.IsZero() {
+			gas += CreateBySelfdestructGas
+		}
+	} else if rules.IsEIP150 && !db.Exist(beneficiary) {
+		gas += CreateBySelfdestructGas
+	}
+	if !rules.IsLondon && !db.HasSuicided(f.Address) {
+		db.AddRefund(SelfdestructRefundGas)
+	}
+	return gas, nil
+}
@@ -0,0 +1,84 @@
+package evm
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestMemoryGasCost(t *testing.T) {
+	tests := []struct {
+		size uint64 // bytes already paid for
+		grow uint64 // bytes to expand to
+		want uint64
+	}{
+		{0, 0, 0},
+		{0, 32, 3},
+		{0, 1056, 101}, // 33 words: 3*33 + 33*33/512
+		{32, 1056, 98},
+		{1056, 64, 0},
+		{0, 32 << 10, 5120}, // 1024 words: 3*1024 + 1024*1024/512
+	}
+	for _, tt := range tests {
+		mem := newMemory()
+		if _, err := memoryGasCost(mem, tt.size); err != nil {
+			t.Fatal(err)
+		}
+		mem.Resize(tt.size)
+		got, err := memoryGasCost(mem, tt.grow)
+		if err != nil || got != tt.want {
+			t.Errorf("memoryGasCost from %d to %d = %d, %v; want %d", tt.size, tt.grow, got, err, tt.want)
+		}
+	}
+	if _, err := memoryGasCost(newMemory(), maxMemorySize+32); err != ErrGasUintOverflow {
+		t.Errorf("memoryGasCost past maxMemorySize: err = %v; want %v", err, ErrGasUintOverflow)
+	}
+}
+
+// TestDynamicGas checks the gas used by short programs, which is their static
+// gas plus the dynamic gas of their last instruction.
+func TestDynamicGas(t *testing.T) {
+	tests := []struct {
+		name string
+		fork Fork
+		code string
+		want uint64
+	}{
+		// PUSH1 1, PUSH1 0, MSTORE: one word of memory.
+		{"MSTORE", Cancun, "6001600052", 12},
+		// PUSH1 1, PUSH2 0x400, MSTORE: 33 words of memory.
+		{"MSTORE/33 words", Cancun, "600161040052", 110},
+		// PUSH2 0x100, PUSH1 2, EXP: a two-byte exponent.
+		{"EXP", Cancun, "6101006002" + "0a", 116},
+		{"EXP/Frontier", Frontier, "6101006002" + "0a", 36},
+		{"EXP/zero exponent", Cancun, "60006002" + "0a", 16},
+		// PUSH1 0x40, PUSH1 0, SHA3: two words hashed and of memory.
+		{"SHA3", Cancun, "60406000" + "20", 54},
+		// PUSH1 0x21, PUSH1 0, PUSH1 0, CALLDATACOPY: two words copied.
+		{"CALLDATACOPY", Cancun, "602160006000" + "37", 24},
+		// PUSH1 0, PUSH1 0x20, PUSH1 0, LOG1: one topic, 32 bytes.
+		{"LOG1", Cancun, "600060206000" + "a1", 1018},
+		// PUSH1 0xaa, BALANCE
+		{"BALANCE/cold", Cancun, "60aa" + "31", 2603},
+		{"BALANCE/warm", Cancun, "60aa31" + "60aa31", 2706},
+		{"BALANCE/Frontier", Frontier, "60aa" + "31", 23},
+		{"BALANCE/Istanbul", Istanbul, "60aa" + "31", 703},
+		// PUSH1 0 (x5), PUSH1 0xaa, PUSH1 0, CALL: no value, no gas.
+		{"CALL/cold", Cancun, "6000600060006000600060aa6000" + "f1", 2621},
+		{"CALL/Frontier new account", Frontier, "6000600060006000600060aa6000" + "f1", 25061},
+		{"CALL/TangerineWhistle new account", TangerineWhistle, "6000600060006000600060aa6000" + "f1", 25721},
+	}
+	for _, tt := range tests {
+		code, err := hex.DecodeString(tt.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := NewInterpreter(WithFork(tt.fork)).Run(&ExecutionContext{Code: code, Gas: 100000})
+		if res.Err != nil {
+			t.Errorf("%s: %v", tt.name, res.Err)
+			continue
+		}
+		if res.GasUsed != tt.want {
+			t.Errorf("%s: used %d gas; want %d", tt.name, res.GasUsed, tt.want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package evm
+
+// A Tracer is notified before each instruction is executed.
+type Tracer interface {
+	CaptureState(pc uint64, op byte, gas, cost uint64, f *Frame)
+}
+
+// A CallTracer is a Tracer that is also told when a call or create fails,
+// with the reason it failed. This includes calls that fail without running
+// any code, such as those beyond CallCreateDepth.
+type CallTracer interface {
+	Tracer
+	CaptureCallFailure(pc uint64, op byte, reason HaltReason, err error, f *Frame)
+}
+
+// A Frame is the machine state of a running execution.
+type Frame struct {
+	CallContext
+	Code   []byte // immutable bytecode being executed
+	PC     uint64 // offset of the current instruction in Code
+	Gas    uint64 // gas remaining
+	Stack  *Stack
+	Memory *Memory
+
+	ReturnData     []byte // output set by RETURN or REVERT
+	LastReturnData []byte // output of the last call this frame made
+
+	in          *Interpreter
+	tx          *TxContext
+	block       *BlockContext
+	depth       int    // number of calls above this frame
+	readOnly    bool   // inside a STATICCALL, where state must not change
+	callGasTemp uint64 // gas for the next call, worked out by its gasFunc
+	jumpdests   bitvec // valid jump destinations, analyzed on first jump
+}
+
+// op returns the instruction at PC. Past the end of the code it is STOP.
+func (f *Frame) op() byte {
+	if f.PC < uint64(len(f.Code)) {
+		return f.Code[f.PC]
+	}
+	return 0x00
+}
+
+// jump moves execution to dest, which must be a JUMPDEST.
+func (f *Frame) jump(dest *Word) error {
+	if f.jumpdests == nil {
+		f.jumpdests = jumpdests.get(f.Code)
+	}
+	if !dest.IsUint64() || dest[0] >= uint64(len(f.Code)) || !f.jumpdests.isSet(dest[0]) {
+		return ErrInvalidJump
+	}
+	f.PC = dest[0]
+	return nil
+}
+
+// A CallContext describes the message that started an execution.
+type CallContext struct {
+	Address Address // account whose code runs and whose storage it uses
+	Caller  Address // account that sent the message
+	Value   Word    // wei sent along with the message
+	Input   []byte  // calldata
+}
+
+// A TxContext holds the values that are the same for every execution within
+// a transaction.
+type TxContext struct {
+	Origin   Address // externally owned account that signed the transaction
+	GasPrice Word    // effective price per unit of gas, in wei
+
+	// BlobHashes are the versioned hashes of the transaction's blobs, read
+	// by BLOBHASH from Cancun (EIP-4844).
+	BlobHashes []Word
+}
+
+// A BlockContext holds the values that describe the block a transaction is
+// included in.
+type BlockContext struct {
+	Coinbase   Address // beneficiary of the block's fees
+	Timestamp  uint64
+	Number     uint64
+	Difficulty Word // read by DIFFICULTY before the Merge
+	Random     Word // read by PREVRANDAO, the same opcode, from the Merge
+	GasLimit   uint64
+	ChainID    Word
+	BaseFee    Word // from London
+	// BlobBaseFee is the price per unit of blob gas, from Cancun (EIP-7516).
+	BlobBaseFee Word
+
+	// GetHash returns the hash of block number n, which BLOCKHASH only asks
+	// for the 256 most recent blocks. If it is nil, BLOCKHASH returns zero.
+	GetHash func(n uint64) Word
+}
+
+// An ExecutionContext is the input to a single run of the interpreter.
+type ExecutionContext struct {
+	CallContext
+	TxContext
+	BlockContext
+	Code []byte // bytecode to execute
+	Gas  uint64 // gas budget
+
+	// Fork, if not nil, selects the fork to run under instead of the one
+	// the Interpreter was configured with.
+	Fork *Fork
+}
+
+// A Result is the outcome of running an ExecutionContext.
+type Result struct {
+	Stack      []Word     // final stack, top of stack first; nil if execution failed
+	ReturnData []byte     // output of RETURN or REVERT
+	Logs       []*Log     // logs emitted, none if execution failed
+	GasUsed    uint64     // gas consumed, all of it unless execution succeeded or reverted
+	Refund     uint64     // gas refunded from GasUsed at the end of the transaction
+	Halt       HaltReason // why execution stopped
+	Err        error      // an *ExecutionError, ErrUnsupportedFork, or nil if Halt is HaltSuccess
+}
+
+// A Log is an event emitted by one of the LOG instructions.
+type Log struct {
+	Address Address // account whose code emitted the log
+	Topics  []Word
+	Data    []byte
+}
+
+// Failed reports whether execution ended with an error.
+func (r *Result) Failed() bool {
+	return r.Err != nil
+}
+
+// An Interpreter executes EVM bytecode. It is configured once with
+// NewInterpreter and can then Run any number of ExecutionContexts.
+type Interpreter struct {
+	rules                 ChainRules
+	state                 StateDB
+	tracer                Tracer
+	unlimitedGas          bool
+	noBalanceCheck        bool
+	immediateSelfDestruct bool
+
+	table *JumpTable
+}
+
+// Rules returns the fork rules the interpreter was configured with, which an
+// ExecutionContext can override for a single Run.
+func (in *Interpreter) Rules() ChainRules {
+	return in.rules
+}
+
+// StateDB returns the world state the interpreter executes against.
+func (in *Interpreter) StateDB() StateDB {
+	return in.state
+}
+
+// An Option configures an Interpreter.
+type Option func(*Interpreter)
+
+// WithFork selects the hard fork whose rules govern which opcodes exist and
+// what they cost. The default is LatestFork. If f is not Supported, every Run
+// fails with ErrUnsupportedFork unless its ExecutionContext selects a fork.
+func WithFork(f Fork) Option {
+	return func(in *Interpreter) {
+		in.rules = f.Rules()
+	}
+}
+
+// WithStateDB sets the world state that executions read and modify. The
+// default is an empty MemoryStateDB.
+func WithStateDB(db StateDB) Option {
+	return func(in *Interpreter) {
+		in.state = db
+	}
+}
+
+// WithTracer sets a Tracer that observes every executed instruction. If t is
+// also a CallTracer, it observes failed calls and creates too.
+func WithTracer(t Tracer) Option {
+	return func(in *Interpreter) {
+		in.tracer = t
+	}
+}
+
+// WithUnlimitedGas runs every execution with an unlimited gas budget,
+// ignoring ExecutionContext.Gas and the gas that calls ask to forward. Gas is
+// still accounted for in Result.GasUsed, but GAS reports 2^256-1 as the
+// evm.json tests expect. Memory is limited to 32 MiB, which no real budget
+// pays for; growing it further fails with ErrOutOfGas.
+func WithUnlimitedGas() Option {
+	return func(in *Interpreter) {
+		in.unlimitedGas = true
+	}
+}
+
+// WithoutBalanceCheck lets calls and creates send value their sender does
+// not have, without debiting it, as some evm.json tests expect.
+func WithoutBalanceCheck() Option {
+	return func(in *Interpreter) {
+		in.noBalanceCheck = true
+	}
+}
+
+// WithImmediateSelfDestruct makes SELFDESTRUCT delete the account at once,
+// under every fork, rather than at the end of the transaction and, from
+// Cancun, only if the account was created in the same transaction. The
+// evm.json tests expect this simplification.
+func WithImmediateSelfDestruct() Option {
+	return func(in *Interpreter) {
+		in.immediateSelfDestruct = true
+	}
+}
+
+// NewInterpreter returns an Interpreter configured with opts.
+func NewInterpreter(opts ...Option) *Interpreter {
+	in := &Interpreter{rules: LatestFork.Rules()}
+	for _, opt := range opts {
+		opt(in)
+	}
+	if in.state == nil {
+		in.state = NewMemoryStateDB()
+	}
+	if in.rules.Fork.Supported() {
+		in.table = &instructionSets[in.rules.Fork]
+	}
+	return in
+}
+
+// withFork returns a copy of in that runs under fork, sharing its state and
+// tracer. The copy has no jump table if fork is not Supported.
+func (in *Interpreter) withFork(fork Fork) *Interpreter {
+	cp := *in
+	cp.rules = fork.Rules()
+	cp.table = nil
+	if fork.Supported() {
+		cp.table = &instructionSets[fork]
+	}
+	return &cp
+}
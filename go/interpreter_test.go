@@ -0,0 +1,256 @@
+package evm
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestHaltReason(t *testing.T) {
+	tests := []struct {
+		name string
+		code []byte
+		gas  uint64
+		want HaltReason
+		err  error
+		pc   uint64
+	}{
+		{"stop", []byte{0x60, 0x01, 0x00}, 100, HaltSuccess, nil, 0},
+		{"invalid", []byte{0x60, 0x01, 0xfe}, 100, HaltInvalidOpcode, ErrInvalidOpcode, 2},
+		{"underflow", []byte{0x60, 0x01, 0x01}, 100, HaltStackUnderflow, ErrStackUnderflow, 2},
+		{"bad jump", []byte{0x60, 0x03, 0x56, 0x00}, 100, HaltBadJumpDestination, ErrInvalidJump, 2},
+		{"return data", []byte{0x60, 0x01, 0x60, 0x00, 0x60, 0x00, 0x3e}, 100, HaltReturnDataOutOfBounds, ErrReturnDataOutOfBounds, 6},
+		{"out of gas", []byte{0x60, 0x01, 0x60, 0x01, 0x01}, 8, HaltOutOfGas, ErrOutOfGas, 4},
+		// CREATE2 of 0xffffff bytes of init code.
+		{"init code size", []byte{0x60, 0x00, 0x62, 0xff, 0xff, 0xff, 0x60, 0x00, 0x60, 0x00, 0xf5}, 100000, HaltInitCodeSize, ErrMaxInitCodeSizeExceeded, 10},
+	}
+	in := NewInterpreter()
+	for _, tt := range tests {
+		res := in.Run(&ExecutionContext{Code: tt.code, Gas: tt.gas})
+		if res.Halt != tt.want {
+			t.Errorf("%s: Halt = %v; want %v", tt.name, res.Halt, tt.want)
+		}
+		if tt.err == nil {
+			if res.Err != nil {
+				t.Errorf("%s: Err = %v; want nil", tt.name, res.Err)
+			}
+			continue
+		}
+		if res.Stack != nil {
+			t.Errorf("%s: Stack = %v; want nil", tt.name, res.Stack)
+		}
+		var execErr *ExecutionError
+		if !errors.As(res.Err, &execErr) || !errors.Is(res.Err, tt.err) {
+			t.Errorf("%s: Err = %v; want ExecutionError wrapping %v", tt.name, res.Err, tt.err)
+			continue
+		}
+		if execErr.PC != tt.pc || execErr.Op != tt.code[tt.pc] {
+			t.Errorf("%s: failed at pc %d op %#x; want pc %d op %#x", tt.name, execErr.PC, execErr.Op, tt.pc, tt.code[tt.pc])
+		}
+	}
+}
+
+// TestSStoreGas runs the net gas metering examples of EIP-2200, excluding
+// the intrinsic transaction gas.
+func TestSStoreGas(t *testing.T) {
+	tests := []struct {
+		code     string
+		used     uint64
+		refund   uint64
+		original uint64
+	}{
+		{"60006000556000600055", 1612, 0, 0},
+		{"60006000556001600055", 20812, 0, 0},
+		{"60016000556000600055", 20812, 19200, 0},
+		{"60016000556002600055", 20812, 0, 0},
+		{"60016000556001600055", 20812, 0, 0},
+		{"60006000556000600055", 5812, 15000, 1},
+		{"60006000556001600055", 5812, 4200, 1},
+		{"60006000556002600055", 5812, 0, 1},
+		{"60026000556000600055", 5812, 15000, 1},
+		{"60026000556003600055", 5812, 0, 1},
+		{"60026000556001600055", 5812, 4200, 1},
+		{"60026000556002600055", 5812, 0, 1},
+		{"60016000556000600055", 5812, 15000, 1},
+		{"60016000556002600055", 5812, 0, 1},
+		{"60016000556001600055", 1612, 0, 1},
+		{"600160005560006000556001600055", 40818, 19200, 0},
+		{"600060005560016000556000600055", 10818, 19200, 1},
+	}
+	for _, tt := range tests {
+		code, err := hex.DecodeString(tt.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db := NewMemoryStateDB()
+		db.SetState(Address{}, new(Word), NewWord(tt.original))
+		res := NewInterpreter(WithFork(Istanbul), WithStateDB(db)).Run(&ExecutionContext{Code: code, Gas: 100000})
+		if res.Err != nil {
+			t.Errorf("%s (original %d): %v", tt.code, tt.original, res.Err)
+			continue
+		}
+		if res.GasUsed != tt.used || db.GetRefund() != tt.refund {
+			t.Errorf("%s (original %d): used %d, refund %d; want %d, %d", tt.code, tt.original, res.GasUsed, db.GetRefund(), tt.used, tt.refund)
+		}
+	}
+}
+
+func TestBlockHash(t *testing.T) {
+	ctx := &ExecutionContext{Gas: 100000}
+	ctx.Number = 1000
+	ctx.GetHash = func(n uint64) Word { return *NewWord(n + 1) }
+	in := NewInterpreter()
+	for _, tt := range []struct{ n, want uint64 }{
+		{999, 1000},
+		{744, 745},
+		{743, 0},
+		{1000, 0},
+		{1001, 0},
+	} {
+		// PUSH2 n, BLOCKHASH
+		ctx.Code = []byte{0x61, byte(tt.n >> 8), byte(tt.n), 0x40}
+		res := in.Run(ctx)
+		if res.Err != nil || len(res.Stack) != 1 || res.Stack[0] != *NewWord(tt.want) {
+			t.Errorf("BLOCKHASH(%d) = %v, %v; want %d", tt.n, res.Stack, res.Err, tt.want)
+		}
+	}
+}
+
+// TestCallDepth runs a contract that counts its invocations in storage and
+// then calls itself, which stops at the call depth limit.
+// failureTracer records the halt reasons of failed calls and creates.
+type failureTracer struct {
+	reasons []HaltReason
+}
+
+func (*failureTracer) CaptureState(pc uint64, op byte, gas, cost uint64, f *Frame) {}
+
+func (t *failureTracer) CaptureCallFailure(pc uint64, op byte, reason HaltReason, err error, f *Frame) {
+	t.reasons = append(t.reasons, reason)
+}
+
+func TestCallDepth(t *testing.T) {
+	// PUSH1 0, SLOAD, PUSH1 1, ADD, PUSH1 0, SSTORE,
+	// PUSH1 0 (x5), ADDRESS, GAS, CALL
+	code, _ := hex.DecodeString("600054600101600055" + "60006000600060006000305af1")
+	self := Address{0xc0, 0xde}
+	db := NewMemoryStateDB()
+	db.SetCode(self, code)
+	ctx := &ExecutionContext{Code: code}
+	ctx.Address = self
+
+	tracer := new(failureTracer)
+	res := NewInterpreter(WithUnlimitedGas(), WithStateDB(db), WithTracer(tracer)).Run(ctx)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if got, want := db.GetState(self, new(Word)), *NewWord(CallCreateDepth + 1); got != want {
+		t.Errorf("ran %v frames; want %v", &got, &want)
+	}
+	if want := []HaltReason{HaltDepthLimit}; len(tracer.reasons) != 1 || tracer.reasons[0] != want[0] {
+		t.Errorf("failed calls = %v; want %v", tracer.reasons, want)
+	}
+}
+
+// TestCreateInvalidCode deploys code starting with 0xEF, which is rejected
+// from London (EIP-3541).
+func TestCreateInvalidCode(t *testing.T) {
+	// Init code: PUSH1 0xef, PUSH1 0, MSTORE8, PUSH1 1, PUSH1 0, RETURN.
+	// The code below stores it in memory and runs CREATE on it.
+	code, _ := hex.DecodeString("6960ef60005360016000f3600052" + "600a60166000f0")
+	for _, tt := range []struct {
+		fork    Fork
+		created bool
+	}{
+		{Berlin, true},
+		{London, false},
+	} {
+		res := NewInterpreter(WithFork(tt.fork)).Run(&ExecutionContext{Code: code, Gas: 100000})
+		if res.Err != nil {
+			t.Fatalf("%v: %v", tt.fork, res.Err)
+		}
+		if created := !res.Stack[0].IsZero(); created != tt.created {
+			t.Errorf("%v: created = %t; want %t", tt.fork, created, tt.created)
+		}
+	}
+}
+
+func TestSelfDestruct(t *testing.T) {
+	// PUSH20 beneficiary, SELFDESTRUCT
+	beneficiary := Address{0xbe}
+	code, _ := hex.DecodeString("73" + hex.EncodeToString(beneficiary[:]) + "ff")
+	self := Address{0xc0, 0xde}
+	tests := []struct {
+		fork    Fork
+		deleted bool
+	}{
+		{Shanghai, true},
+		{Cancun, false}, // EIP-6780: not created in this transaction
+	}
+	for _, tt := range tests {
+		db := NewMemoryStateDB()
+		db.SetCode(self, code)
+		db.SetBalance(self, NewWord(7))
+		ctx := &ExecutionContext{Code: code, Gas: 100000}
+		ctx.Address = self
+
+		res := NewInterpreter(WithFork(tt.fork), WithStateDB(db)).Run(ctx)
+		if res.Err != nil {
+			t.Fatalf("%v: %v", tt.fork, res.Err)
+		}
+		if got := !db.Exist(self); got != tt.deleted {
+			t.Errorf("%v: deleted = %v; want %v", tt.fork, got, tt.deleted)
+		}
+		if got := db.GetBalance(beneficiary); got != *NewWord(7) {
+			t.Errorf("%v: beneficiary balance = %v; want 7", tt.fork, &got)
+		}
+	}
+}
+
+func TestUnlimitedGasMemory(t *testing.T) {
+	// PUSH1 0, PUSH5 0x1fffffffc0, MSTORE: affordable with 2^64-1 gas, but 128 GiB.
+	code, _ := hex.DecodeString("6000641fffffffc052")
+	res := NewInterpreter(WithUnlimitedGas()).Run(&ExecutionContext{Code: code})
+	if !errors.Is(res.Err, ErrOutOfGas) {
+		t.Errorf("Err = %v; want %v", res.Err, ErrOutOfGas)
+	}
+}
+
+func TestTransientStorage(t *testing.T) {
+	// PUSH1 0, TLOAD, PUSH1 7, PUSH1 0, TSTORE, PUSH1 0, TLOAD
+	code, _ := hex.DecodeString("60005c" + "600760005d" + "60005c")
+	in := NewInterpreter()
+	for run := 0; run < 2; run++ {
+		res := in.Run(&ExecutionContext{Code: code, Gas: 100000})
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		// The slot is empty at the start of every transaction.
+		if want := []Word{*NewWord(7), {}}; len(res.Stack) != 2 || res.Stack[0] != want[0] || res.Stack[1] != want[1] {
+			t.Errorf("run %d: stack = %v; want %v", run, res.Stack, want)
+		}
+		if want := uint64(4*3 + 3*100); res.GasUsed != want {
+			t.Errorf("run %d: used %d gas; want %d", run, res.GasUsed, want)
+		}
+	}
+}
+
+func TestMCopy(t *testing.T) {
+	// PUSH32 0x0102..20, PUSH1 0, MSTORE, then MCOPY 32 bytes from 0 to 1
+	// and MLOAD both words.
+	word := "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"
+	code, _ := hex.DecodeString("7f" + word + "600052" + "602060006001" + "5e" + "602051" + "600051")
+	res := NewInterpreter().Run(&ExecutionContext{Code: code, Gas: 100000})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	want := []string{
+		"0x10102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"0x2000000000000000000000000000000000000000000000000000000000000000",
+	}
+	for i, w := range want {
+		if got := res.Stack[i].Hex(); got != w {
+			t.Errorf("word %d = %s; want %s", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package evm
+
+// A journalEntry records one modification of a MemoryStateDB so that it can
+// be undone.
+type journalEntry interface {
+	revert(s *MemoryStateDB)
+}
+
+// A journal is the list of modifications made during the current
+// transaction, oldest first.
+type journal []journalEntry
+
+type (
+	// createAccountChange undoes CreateAccount and implicit account
+	// creation. prev is the replaced account, nil if there was none.
+	createAccountChange struct {
+		addr Address
+		prev *account
+	}
+	deleteAccountChange struct {
+		addr Address
+		prev *account
+	}
+	balanceChange struct {
+		addr Address
+		prev Word
+	}
+	nonceChange struct {
+		addr Address
+		prev uint64
+	}
+	codeChange struct {
+		addr     Address
+		prev     []byte
+		prevHash Word
+	}
+	storageChange struct {
+		addr      Address
+		key, prev Word
+	}
+	transientStorageChange struct {
+		addr      Address
+		key, prev Word
+	}
+	refundChange struct {
+		prev uint64
+	}
+	addLogChange  struct{}
+	suicideChange struct {
+		addr        Address
+		prev        bool
+		prevBalance Word
+	}
+	accessListAddAccountChange struct {
+		addr Address
+	}
+	accessListAddSlotChange struct {
+		addr Address
+		slot Word
+	}
+)
+
+func (ch createAccountChange) revert(s *MemoryStateDB) {
+	if ch.prev == nil {
+		delete(s.accounts, ch.addr)
+	} else {
+		s.accounts[ch.addr] = ch.prev
+	}
+}
+
+func (ch deleteAccountChange) revert(s *MemoryStateDB) {
+	s.accounts[ch.addr] = ch.prev
+}
+
+func (ch balanceChange) revert(s *MemoryStateDB) {
+	s.accounts[ch.addr].balance = ch.prev
+}
+
+func (ch nonceChange) revert(s *MemoryStateDB) {
+	s.accounts[ch.addr].nonce = ch.prev
+}
+
+func (ch codeChange) revert(s *MemoryStateDB) {
+	acc := s.accounts[ch.addr]
+	acc.code, acc.codeHash = ch.prev, ch.prevHash
+}
+
+func (ch storageChange) revert(s *MemoryStateDB) {
+	s.accounts[ch.addr].setState(&ch.key, &ch.prev)
+}
+
+func (ch transientStorageChange) revert(s *MemoryStateDB) {
+	s.setTransientState(ch.addr, &ch.key, &ch.prev)
+}
+
+func (ch refundChange) revert(s *MemoryStateDB) {
+	s.refund = ch.prev
+}
+
+func (ch addLogChange) revert(s *MemoryStateDB) {
+	s.logs = s.logs[:len(s.logs)-1]
+}
+
+func (ch suicideChange) revert(s *MemoryStateDB) {
+	acc := s.accounts[ch.addr]
+	acc.suicided = ch.prev
+	acc.balance = ch.prevBalance
+}
+
+func (ch accessListAddAccountChange) revert(s *MemoryStateDB) {
+	delete(s.accessList, ch.addr)
+}
+
+func (ch accessListAddSlotChange) revert(s *MemoryStateDB) {
+	delete(s.accessList[ch.addr], ch.slot)
+}
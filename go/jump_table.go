@@ -0,0 +1,206 @@
+package evm
+
+// An executionFunc runs one instruction against a frame. The interpreter has
+// already validated the stack and charged gas, and for instructions that
+// have halts set, returning nil ends execution successfully.
+type executionFunc func(f *Frame) error
+
+// An Operation describes how to execute and charge for one opcode.
+type Operation struct {
+	execute     executionFunc
+	constantGas uint64
+	dynamicGas  gasFunc
+	minStack    int // stack items the instruction pops
+	maxStack    int // largest stack size it can run on without overflowing
+	memorySize  memorySizeFunc
+
+	halts  bool // ends execution of the frame
+	writes bool // modifies state
+	jumps  bool // sets PC itself instead of advancing to the next instruction
+}
+
+// A JumpTable maps every opcode to its Operation. Opcodes whose execute is
+// nil are undefined under the table's fork.
+type JumpTable [256]Operation
+
+// minStack returns the stack size an instruction that pops pops items needs.
+func minStack(pops, pushes int) int {
+	return pops
+}
+
+// maxStack returns the largest stack size on which an instruction that pops
+// pops items and pushes pushes items stays within stackLimit.
+func maxStack(pops, pushes int) int {
+	return stackLimit + pops - pushes
+}
+
+// instructionSets holds the jump table of every fork, built once at init.
+var instructionSets = func() (sets [LatestFork + 1]JumpTable) {
+	for fork := Frontier; fork <= LatestFork; fork++ {
+		sets[fork] = newInstructionSet(fork.Rules())
+	}
+	return sets
+}()
+
+// newInstructionSet returns the jump table for the instructions that exist
+// under rules.
+func newInstructionSet(rules ChainRules) JumpTable {
+	var jt JumpTable
+
+	jt[0x00] = Operation{execute: Stop, constantGas: GasZero, minStack: minStack(0, 0), maxStack: maxStack(0, 0), halts: true}
+	jt[0x01] = Operation{execute: Add, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x02] = Operation{execute: Mul, constantGas: GasFastStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x03] = Operation{execute: Sub, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x04] = Operation{execute: Div, constantGas: GasFastStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x05] = Operation{execute: SDiv, constantGas: GasFastStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x06] = Operation{execute: Mod, constantGas: GasFastStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x07] = Operation{execute: SMod, constantGas: GasFastStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x08] = Operation{execute: AddMod, constantGas: GasMidStep, minStack: minStack(3, 1), maxStack: maxStack(3, 1)}
+	jt[0x09] = Operation{execute: MulMod, constantGas: GasMidStep, minStack: minStack(3, 1), maxStack: maxStack(3, 1)}
+	jt[0x0a] = Operation{execute: Exp, constantGas: GasSlowStep, dynamicGas: makeGasExp(ExpByteGasFrontier), minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x0b] = Operation{execute: SignExtend, constantGas: GasFastStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+
+	jt[0x10] = Operation{execute: Lt, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x11] = Operation{execute: Gt, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x12] = Operation{execute: SLt, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x13] = Operation{execute: SGt, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x14] = Operation{execute: Eq, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x15] = Operation{execute: IsZero, constantGas: GasFastestStep, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+	jt[0x16] = Operation{execute: And, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x17] = Operation{execute: Or, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x18] = Operation{execute: Xor, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+	jt[0x19] = Operation{execute: Not, constantGas: GasFastestStep, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+	jt[0x1a] = Operation{execute: Byte, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+
+	jt[0x20] = Operation{execute: Sha3, constantGas: Sha3Gas, dynamicGas: gasSha3, memorySize: memorySha3, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+
+	jt[0x30] = Operation{execute: AddressOp, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x31] = Operation{execute: Balance, constantGas: BalanceGasFrontier, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+	jt[0x32] = Operation{execute: Origin, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x33] = Operation{execute: Caller, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x34] = Operation{execute: CallValue, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x35] = Operation{execute: CallDataLoad, constantGas: GasFastestStep, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+	jt[0x36] = Operation{execute: CallDataSize, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x37] = Operation{execute: CallDataCopy, constantGas: GasFastestStep, dynamicGas: memoryCopierGas(2), memorySize: memoryCopy, minStack: minStack(3, 0), maxStack: maxStack(3, 0)}
+	jt[0x38] = Operation{execute: CodeSize, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x39] = Operation{execute: CodeCopy, constantGas: GasFastestStep, dynamicGas: memoryCopierGas(2), memorySize: memoryCopy, minStack: minStack(3, 0), maxStack: maxStack(3, 0)}
+	jt[0x3a] = Operation{execute: GasPrice, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x3b] = Operation{execute: ExtCodeSize, constantGas: ExtcodeSizeGas, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+	jt[0x3c] = Operation{execute: ExtCodeCopy, constantGas: ExtcodeSizeGas, dynamicGas: memoryCopierGas(3), memorySize: memoryExtCodeCopy, minStack: minStack(4, 0), maxStack: maxStack(4, 0)}
+
+	jt[0x40] = Operation{execute: BlockHash, constantGas: BlockHashGas, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+	jt[0x41] = Operation{execute: Coinbase, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x42] = Operation{execute: Timestamp, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x43] = Operation{execute: Number, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x44] = Operation{execute: Difficulty, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x45] = Operation{execute: GasLimit, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+
+	jt[0x50] = Operation{execute: Pop, constantGas: GasQuickStep, minStack: minStack(1, 0), maxStack: maxStack(1, 0)}
+	jt[0x51] = Operation{execute: MLoad, constantGas: GasFastestStep, dynamicGas: gasMemory, memorySize: memoryMLoad, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+	jt[0x52] = Operation{execute: MStore, constantGas: GasFastestStep, dynamicGas: gasMemory, memorySize: memoryMStore, minStack: minStack(2, 0), maxStack: maxStack(2, 0)}
+	jt[0x53] = Operation{execute: MStore8, constantGas: GasFastestStep, dynamicGas: gasMemory, memorySize: memoryMStore8, minStack: minStack(2, 0), maxStack: maxStack(2, 0)}
+	jt[0x54] = Operation{execute: SLoad, constantGas: SloadGasFrontier, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+	jt[0x55] = Operation{execute: SStore, dynamicGas: gasSStoreLegacy, minStack: minStack(2, 0), maxStack: maxStack(2, 0), writes: true}
+	jt[0x56] = Operation{execute: Jump, constantGas: GasMidStep, minStack: minStack(1, 0), maxStack: maxStack(1, 0), jumps: true}
+	jt[0x57] = Operation{execute: JumpI, constantGas: GasSlowStep, minStack: minStack(2, 0), maxStack: maxStack(2, 0), jumps: true}
+	jt[0x58] = Operation{execute: PC, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x59] = Operation{execute: MSize, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x5a] = Operation{execute: Gas, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	jt[0x5b] = Operation{execute: JumpDest, constantGas: GasJumpDest, minStack: minStack(0, 0), maxStack: maxStack(0, 0)}
+
+	jt[0xff] = Operation{execute: SelfDestruct, dynamicGas: gasSelfdestruct, minStack: minStack(1, 0), maxStack: maxStack(1, 0), halts: true, writes: true}
+
+	for n := 0; n <= 4; n++ {
+		jt[0xa0+n] = Operation{execute: makeLog(n), dynamicGas: makeGasLog(uint64(n)), memorySize: memoryLog, minStack: minStack(n+2, 0), maxStack: maxStack(n+2, 0), writes: true}
+	}
+	jt[0xf0] = Operation{execute: Create, constantGas: CreateGas, dynamicGas: gasMemory, memorySize: memoryCreate, minStack: minStack(3, 1), maxStack: maxStack(3, 1), writes: true}
+	jt[0xf1] = Operation{execute: Call, constantGas: CallGasFrontier, dynamicGas: gasCall, memorySize: memoryCall, minStack: minStack(7, 1), maxStack: maxStack(7, 1)}
+	jt[0xf2] = Operation{execute: CallCode, constantGas: CallGasFrontier, dynamicGas: gasCallCode, memorySize: memoryCall, minStack: minStack(7, 1), maxStack: maxStack(7, 1)}
+	jt[0xf3] = Operation{execute: Return, dynamicGas: gasMemory, memorySize: memoryReturn, minStack: minStack(2, 0), maxStack: maxStack(2, 0), halts: true}
+
+	for size := 1; size <= 32; size++ {
+		jt[0x5f+size] = Operation{execute: makePush(uint64(size)), constantGas: GasFastestStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	}
+	for n := 1; n <= 16; n++ {
+		jt[0x7f+n] = Operation{execute: makeDup(n), constantGas: GasFastestStep, minStack: minStack(n, n+1), maxStack: maxStack(n, n+1)}
+		jt[0x8f+n] = Operation{execute: makeSwap(n), constantGas: GasFastestStep, minStack: minStack(n+1, n+1), maxStack: maxStack(n+1, n+1)}
+	}
+
+	if rules.IsHomestead {
+		jt[0xf4] = Operation{execute: DelegateCall, constantGas: CallGasFrontier, dynamicGas: gasDelegateCall, memorySize: memoryDelegateCall, minStack: minStack(6, 1), maxStack: maxStack(6, 1)}
+	}
+	if rules.IsEIP150 {
+		jt[0x31].constantGas = BalanceGasEIP150
+		jt[0x3b].constantGas = ExtcodeSizeGasEIP150
+		jt[0x3c].constantGas = ExtcodeSizeGasEIP150
+		jt[0x54].constantGas = SloadGasEIP150
+		jt[0xf1].constantGas = CallGasEIP150
+		jt[0xf2].constantGas = CallGasEIP150
+		jt[0xf4].constantGas = CallGasEIP150
+		jt[0xff].constantGas = SelfdestructGasEIP150
+	}
+	if rules.IsEIP158 {
+		jt[0x0a].dynamicGas = makeGasExp(ExpByteGasEIP158)
+	}
+	if rules.IsByzantium {
+		jt[0x3d] = Operation{execute: ReturnDataSize, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+		jt[0x3e] = Operation{execute: ReturnDataCopy, constantGas: GasFastestStep, dynamicGas: memoryCopierGas(2), memorySize: memoryCopy, minStack: minStack(3, 0), maxStack: maxStack(3, 0)}
+		jt[0xfa] = Operation{execute: StaticCall, constantGas: CallGasEIP150, dynamicGas: gasStaticCall, memorySize: memoryDelegateCall, minStack: minStack(6, 1), maxStack: maxStack(6, 1)}
+		jt[0xfd] = Operation{execute: Revert, dynamicGas: gasMemory, memorySize: memoryReturn, minStack: minStack(2, 0), maxStack: maxStack(2, 0), halts: true}
+	}
+	if rules.IsConstantinople {
+		jt[0x1b] = Operation{execute: Shl, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+		jt[0x1c] = Operation{execute: Shr, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+		jt[0x1d] = Operation{execute: Sar, constantGas: GasFastestStep, minStack: minStack(2, 1), maxStack: maxStack(2, 1)}
+		jt[0x3f] = Operation{execute: ExtCodeHash, constantGas: ExtcodeHashGas, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+		jt[0xf5] = Operation{execute: Create2, constantGas: CreateGas, dynamicGas: gasCreate2, memorySize: memoryCreate, minStack: minStack(4, 1), maxStack: maxStack(4, 1), writes: true}
+	}
+	if rules.IsConstantinople && !rules.IsPetersburg {
+		jt[0x55].dynamicGas = makeGasSStore(sstoreGas{noop: NetSstoreNoopGas, clearRefund: SstoreClearsScheduleRefund})
+	}
+	if rules.IsIstanbul {
+		jt[0x31].constantGas = BalanceGasEIP1884
+		jt[0x3f].constantGas = ExtcodeHashGasEIP1884
+		jt[0x54].constantGas = SloadGasEIP1884
+		jt[0x55].dynamicGas = makeGasSStore(sstoreGas{sentry: true, noop: SloadGasEIP1884, clearRefund: SstoreClearsScheduleRefund})
+		jt[0x46] = Operation{execute: ChainID, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+		jt[0x47] = Operation{execute: SelfBalance, constantGas: GasFastStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	}
+	if rules.IsBerlin {
+		jt[0x31].constantGas = WarmStorageReadCost
+		jt[0x31].dynamicGas = gasAccountAccessEIP2929
+		jt[0x3b].constantGas = WarmStorageReadCost
+		jt[0x3b].dynamicGas = gasAccountAccessEIP2929
+		jt[0x3c].constantGas = WarmStorageReadCost
+		jt[0x3c].dynamicGas = gasExtCodeCopyEIP2929
+		jt[0x3f].constantGas = WarmStorageReadCost
+		jt[0x3f].dynamicGas = gasAccountAccessEIP2929
+		for _, op := range []byte{0xf1, 0xf2, 0xf4, 0xfa} {
+			jt[op].constantGas = WarmStorageReadCost
+			jt[op].dynamicGas = makeGasCallEIP2929(jt[op].dynamicGas)
+		}
+		jt[0x54].constantGas = 0
+		jt[0x54].dynamicGas = gasSLoadEIP2929
+		jt[0x55].dynamicGas = makeGasSStore(sstoreGas{sentry: true, coldSload: ColdSloadCost, noop: WarmStorageReadCost, clearRefund: SstoreClearsScheduleRefund})
+	}
+	if rules.IsLondon {
+		jt[0x55].dynamicGas = makeGasSStore(sstoreGas{sentry: true, coldSload: ColdSloadCost, noop: WarmStorageReadCost, clearRefund: SstoreClearsScheduleRefundEIP3529})
+		jt[0x48] = Operation{execute: BaseFee, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	}
+	if rules.IsMerge {
+		jt[0x44].execute = PrevRandao
+	}
+	if rules.IsShanghai {
+		jt[0xf0].dynamicGas = gasCreateEIP3860
+		jt[0xf5].dynamicGas = gasCreate2EIP3860
+		jt[0x5f] = Operation{execute: Push0, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+	}
+	if rules.IsCancun {
+		jt[0x49] = Operation{execute: BlobHash, constantGas: GasFastestStep, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+		jt[0x4a] = Operation{execute: BlobBaseFee, constantGas: GasQuickStep, minStack: minStack(0, 1), maxStack: maxStack(0, 1)}
+		jt[0x5c] = Operation{execute: TLoad, constantGas: WarmStorageReadCost, minStack: minStack(1, 1), maxStack: maxStack(1, 1)}
+		jt[0x5d] = Operation{execute: TStore, constantGas: WarmStorageReadCost, minStack: minStack(2, 0), maxStack: maxStack(2, 0), writes: true}
+		jt[0x5e] = Operation{execute: MCopy, constantGas: GasFastestStep, dynamicGas: memoryCopierGas(2), memorySize: memoryMCopy, minStack: minStack(3, 0), maxStack: maxStack(3, 0)}
+	}
+	return jt
+}
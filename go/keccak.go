@@ -0,0 +1,99 @@
+package evm
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// keccakRate is the number of bytes absorbed per permutation by Keccak-256:
+// the 200-byte state less twice the 32-byte output.
+const keccakRate = 136
+
+// keccakRoundConstants are the iota step constants of the 24 rounds.
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakRotations and keccakPiLanes drive the combined rho and pi steps,
+// which visit the lanes other than (0, 0) in a single cycle.
+var (
+	keccakRotations = [24]int{1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44}
+	keccakPiLanes   = [24]int{10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1}
+)
+
+// keccakF1600 applies the Keccak-f[1600] permutation to a, whose lane (x, y)
+// is a[x+5*y].
+func keccakF1600(a *[25]uint64) {
+	var c [5]uint64
+	for _, rc := range keccakRoundConstants {
+		// theta
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[y+x] ^= d
+			}
+		}
+		// rho and pi
+		t := a[1]
+		for i, lane := range keccakPiLanes {
+			a[lane], t = bits.RotateLeft64(t, keccakRotations[i]), a[lane]
+		}
+		// chi
+		for y := 0; y < 25; y += 5 {
+			copy(c[:], a[y:y+5])
+			for x := 0; x < 5; x++ {
+				a[y+x] = c[x] ^ (^c[(x+1)%5] & c[(x+2)%5])
+			}
+		}
+		// iota
+		a[0] ^= rc
+	}
+}
+
+// Keccak256 returns the Keccak-256 hash of the concatenation of data. This is
+// the hash Ethereum uses everywhere: the original Keccak submission with
+// 0x01 padding, not NIST SHA3-256, which pads with 0x06.
+func Keccak256(data ...[]byte) [32]byte {
+	var (
+		state [25]uint64
+		buf   [keccakRate]byte
+		n     int
+	)
+	absorb := func() {
+		for i := 0; i < keccakRate/8; i++ {
+			state[i] ^= binary.LittleEndian.Uint64(buf[8*i:])
+		}
+		keccakF1600(&state)
+		n = 0
+	}
+	for _, d := range data {
+		for len(d) > 0 {
+			m := copy(buf[n:], d)
+			n += m
+			d = d[m:]
+			if n == keccakRate {
+				absorb()
+			}
+		}
+	}
+	for i := n; i < keccakRate; i++ {
+		buf[i] = 0
+	}
+	buf[n] ^= 0x01
+	buf[keccakRate-1] ^= 0x80
+	absorb()
+
+	var out [32]byte
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(out[8*i:], state[i])
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package evm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	counting := make([]byte, 512)
+	for i := range counting {
+		counting[i] = byte(i)
+	}
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"empty", nil, "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", []byte("abc"), "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"ffffffff", []byte{0xff, 0xff, 0xff, 0xff}, "29045a592007d0c246ef02c2223570da9522d0cf0f73282c79a1bc8f0bb2c238"},
+		// Around the 136-byte block size, where padding spills into a
+		// second block.
+		{"135 bytes", bytes.Repeat([]byte("a"), 135), "34367dc248bbd832f4e3e69dfaac2f92638bd0bbd18f2912ba4ef454919cf446"},
+		{"136 bytes", bytes.Repeat([]byte("a"), 136), "a6c4d403279fe3e0af03729caada8374b5ca54d8065329a3ebcaeb4b60aa386e"},
+		{"137 bytes", bytes.Repeat([]byte("a"), 137), "d869f639c7046b4929fc92a4d988a8b22c55fbadb802c0c66ebcd484f1915f39"},
+		{"512 bytes", counting, "f55ba327291604f0e5be6651752398b7be2331aad65f5763ce067df95cc13be1"},
+	}
+	for _, tt := range tests {
+		got := Keccak256(tt.in)
+		if h := hex.EncodeToString(got[:]); h != tt.want {
+			t.Errorf("Keccak256(%s) = %s; want %s", tt.name, h, tt.want)
+		}
+		// Splitting the input must not change the hash.
+		if len(tt.in) > 0 {
+			split := Keccak256(tt.in[:len(tt.in)/3], tt.in[len(tt.in)/3:])
+			if split != got {
+				t.Errorf("Keccak256(%s) in two parts = %x; want %x", tt.name, split, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package evm
+
+// A Memory is the byte-addressable EVM memory. It starts empty and grows in
+// 32-byte words whenever an instruction touches bytes past its end.
+type Memory struct {
+	store       []byte
+	lastGasCost uint64 // total expansion gas paid for the current size
+}
+
+func newMemory() *Memory {
+	return new(Memory)
+}
+
+// Len returns the size of memory in bytes, as reported by MSIZE. It is always
+// a multiple of 32.
+func (m *Memory) Len() int {
+	return len(m.store)
+}
+
+// Data returns the memory contents. The slice aliases the memory and must not
+// be modified.
+func (m *Memory) Data() []byte {
+	return m.store
+}
+
+// Resize grows memory to size bytes. Memory never shrinks, and size must be a
+// multiple of 32.
+func (m *Memory) Resize(size uint64) {
+	if uint64(len(m.store)) < size {
+		m.store = append(m.store, make([]byte, size-uint64(len(m.store)))...)
+	}
+}
+
+// Set copies value into memory at offset. Memory must already be large enough.
+func (m *Memory) Set(offset uint64, value []byte) {
+	copy(m.store[offset:], value)
+}
+
+// Set32 stores val as a 32-byte big-endian word at offset. Memory must already
+// be large enough.
+func (m *Memory) Set32(offset uint64, val *Word) {
+	val.PutBytes32(m.store[offset : offset+32])
+}
+
+// SetByte stores b at offset. Memory must already be large enough.
+func (m *Memory) SetByte(offset uint64, b byte) {
+	m.store[offset] = b
+}
+
+// SetPadded copies size bytes into memory at offset, taken from src starting
+// at srcOffset. Bytes past the end of src are written as zeros, which is how
+// every *COPY instruction treats out-of-range reads.
+func (m *Memory) SetPadded(offset, size uint64, src []byte, srcOffset uint64) {
+	dst := m.store[offset : offset+size]
+	n := 0
+	if srcOffset < uint64(len(src)) {
+		n = copy(dst, src[srcOffset:])
+	}
+	for i := n; i < len(dst); i++ {
+		dst[i] = 0
+	}
+}
+
+// Copy copies size bytes from src to dst within memory, as MCOPY does. The
+// ranges may overlap. Memory must already be large enough.
+func (m *Memory) Copy(dst, src, size uint64) {
+	copy(m.store[dst:dst+size], m.store[src:src+size])
+}
+
+// GetCopy returns a copy of size bytes starting at offset.
+func (m *Memory) GetCopy(offset, size uint64) []byte {
+	if size == 0 {
+		return nil
+	}
+	out := make([]byte, size)
+	copy(out, m.store[offset:offset+size])
+	return out
+}
+
+// GetPtr returns the size bytes starting at offset without copying them.
+func (m *Memory) GetPtr(offset, size uint64) []byte {
+	if size == 0 {
+		return nil
+	}
+	return m.store[offset : offset+size]
+}
+
+// toWordSize returns the number of 32-byte words needed to hold size bytes.
+func toWordSize(size uint64) uint64 {
+	if size > ^uint64(0)-31 {
+		return ^uint64(0)/32 + 1
+	}
+	return (size + 31) / 32
+}
+
+// calcMemSize returns offset+size, the memory size an access of size bytes at
+// offset needs, or ErrGasUintOverflow if it does not fit in 64 bits. A zero
+// size never touches memory, so its offset is ignored.
+func calcMemSize(offset, size *Word) (uint64, error) {
+	if size.IsZero() {
+		return 0, nil
+	}
+	if !offset.IsUint64() || !size.IsUint64() || offset[0]+size[0] < offset[0] {
+		return 0, ErrGasUintOverflow
+	}
+	return offset[0] + size[0], nil
+}
+
+// A memorySizeFunc returns the memory size in bytes an instruction will touch,
+// read from its stack arguments, so that expansion can be paid for before the
+// instruction runs.
+type memorySizeFunc func(stack *Stack) (uint64, error)
+
+func memorySha3(stack *Stack) (uint64, error) {
+	return calcMemSize(stack.back(0), stack.back(1))
+}
+
+// memoryReturn is the memorySizeFunc of RETURN and REVERT.
+func memoryReturn(stack *Stack) (uint64, error) {
+	return calcMemSize(stack.back(0), stack.back(1))
+}
+
+func memoryLog(stack *Stack) (uint64, error) {
+	return calcMemSize(stack.back(0), stack.back(1))
+}
+
+func memoryMLoad(stack *Stack) (uint64, error) {
+	return calcMemSize(stack.back(0), &Word{32})
+}
+
+func memoryMStore(stack *Stack) (uint64, error) {
+	return calcMemSize(stack.back(0), &Word{32})
+}
+
+func memoryMStore8(stack *Stack) (uint64, error) {
+	return calcMemSize(stack.back(0), &Word{1})
+}
+
+// memoryCopy is the memorySizeFunc of CALLDATACOPY, CODECOPY and
+// RETURNDATACOPY, which take the memory offset first and the size third.
+func memoryCopy(stack *Stack) (uint64, error) {
+	return calcMemSize(stack.back(0), stack.back(2))
+}
+
+func memoryExtCodeCopy(stack *Stack) (uint64, error) {
+	return calcMemSize(stack.back(1), stack.back(3))
+}
+
+// maxMemSize returns the larger of two memory sizes, or the first error.
+func maxMemSize(a, b uint64, errA, errB error) (uint64, error) {
+	if errA != nil {
+		return 0, errA
+	}
+	if errB != nil {
+		return 0, errB
+	}
+	if a > b {
+		return a, nil
+	}
+	return b, nil
+}
+
+// memoryCall is the memorySizeFunc of CALL and CALLCODE, which need memory
+// for both their input and their output.
+func memoryCall(stack *Stack) (uint64, error) {
+	in, errIn := calcMemSize(stack.back(3), stack.back(4))
+	out, errOut := calcMemSize(stack.back(5), stack.back(6))
+	return maxMemSize(in, out, errIn, errOut)
+}
+
+// memoryDelegateCall is the memorySizeFunc of DELEGATECALL and STATICCALL,
+// which take no value argument.
+func memoryDelegateCall(stack *Stack) (uint64, error) {
+	in, errIn := calcMemSize(stack.back(2), stack.back(3))
+	out, errOut := calcMemSize(stack.back(4), stack.back(5))
+	return maxMemSize(in, out, errIn, errOut)
+}
+
+// memoryMCopy is the memorySizeFunc of MCOPY, which needs memory for both the
+// range it reads and the range it writes.
+func memoryMCopy(stack *Stack) (uint64, error) {
+	dst, errDst := calcMemSize(stack.back(0), stack.back(2))
+	src, errSrc := calcMemSize(stack.back(1), stack.back(2))
+	return maxMemSize(dst, src, errDst, errSrc)
+}
+
+// memoryCreate is the memorySizeFunc of CREATE and CREATE2.
+func memoryCreate(stack *Stack) (uint64, error) {
+	return calcMemSize(stack.back(1), stack.back(2))
+}
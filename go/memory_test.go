@@ -0,0 +1,76 @@
+package evm
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMemoryResize(t *testing.T) {
+	m := newMemory()
+	st := newStack()
+	st.push(NewWord(32))
+	size, err := memoryMStore8(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Resize(toWordSize(size) * 32)
+	if m.Len() != 64 {
+		t.Errorf("MSTORE8 at 32 grew memory to %d bytes; want 64", m.Len())
+	}
+	m.Resize(32)
+	if m.Len() != 64 {
+		t.Errorf("Resize(32) shrank memory to %d bytes", m.Len())
+	}
+}
+
+func TestMemorySetPadded(t *testing.T) {
+	tests := []struct {
+		srcOffset uint64
+		want      []byte
+	}{
+		{0, []byte{1, 2, 3, 0}},
+		{2, []byte{3, 0, 0, 0}},
+		{3, []byte{0, 0, 0, 0}},
+		{1 << 40, []byte{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		m := newMemory()
+		m.Resize(32)
+		for i := range m.store {
+			m.store[i] = 0xff
+		}
+		m.SetPadded(1, 4, []byte{1, 2, 3}, tt.srcOffset)
+		if got := m.GetCopy(1, 4); !bytes.Equal(got, tt.want) {
+			t.Errorf("SetPadded with srcOffset %d wrote %x; want %x", tt.srcOffset, got, tt.want)
+		}
+		if m.store[0] != 0xff || m.store[5] != 0xff {
+			t.Errorf("SetPadded with srcOffset %d wrote outside its range", tt.srcOffset)
+		}
+	}
+}
+
+func TestMemoryGetZeroSize(t *testing.T) {
+	m := newMemory()
+	// A zero-size read needs no memory, whatever its offset.
+	if got := m.GetCopy(1<<40, 0); got != nil {
+		t.Errorf("GetCopy(_, 0) = %x; want nil", got)
+	}
+	if got := m.GetPtr(1<<40, 0); got != nil {
+		t.Errorf("GetPtr(_, 0) = %x; want nil", got)
+	}
+}
+
+func TestMemoryGetCopy(t *testing.T) {
+	m := newMemory()
+	m.Resize(32)
+	m.Set(0, []byte{1, 2})
+	got := m.GetCopy(0, 2)
+	got[0] = 9
+	if m.Data()[0] != 1 {
+		t.Error("GetCopy result aliases memory")
+	}
+	m.GetPtr(0, 2)[0] = 9
+	if m.Data()[0] != 9 {
+		t.Error("GetPtr result does not alias memory")
+	}
+}
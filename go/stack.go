@@ -0,0 +1,127 @@
+package evm
+
+import "errors"
+
+// stackLimit is the maximum number of items on the EVM stack.
+const stackLimit = 1024
+
+var (
+	// ErrStackUnderflow is returned when an instruction needs more items than
+	// the stack holds.
+	ErrStackUnderflow = errors.New("stack underflow")
+	// ErrStackOverflow is returned when a push would exceed stackLimit items.
+	ErrStackOverflow = errors.New("stack limit reached")
+)
+
+// A Stack is the EVM operand stack. Items are stored bottom first so that
+// pushes and pops at the top are O(1).
+type Stack struct {
+	data []Word
+}
+
+func newStack() *Stack {
+	return &Stack{data: make([]Word, 0, 16)}
+}
+
+// Len returns the number of items on the stack.
+func (st *Stack) Len() int {
+	return len(st.data)
+}
+
+// Data returns the stack contents, bottom of stack first. The slice aliases
+// the stack and must not be modified.
+func (st *Stack) Data() []Word {
+	return st.data
+}
+
+// Push pushes a copy of w.
+func (st *Stack) Push(w *Word) error {
+	if len(st.data) >= stackLimit {
+		return ErrStackOverflow
+	}
+	st.data = append(st.data, *w)
+	return nil
+}
+
+// Pop removes and returns the top item.
+func (st *Stack) Pop() (Word, error) {
+	if err := st.require(1); err != nil {
+		return Word{}, err
+	}
+	return st.pop(), nil
+}
+
+// Peek returns a pointer to the top item, which may be modified in place.
+func (st *Stack) Peek() (*Word, error) {
+	if err := st.require(1); err != nil {
+		return nil, err
+	}
+	return st.peek(), nil
+}
+
+// Dup pushes a copy of the n-th item from the top, counting from 1.
+func (st *Stack) Dup(n int) error {
+	if err := st.require(n); err != nil {
+		return err
+	}
+	return st.Push(&st.data[len(st.data)-n])
+}
+
+// Swap exchanges the top item with the item n places below it.
+func (st *Stack) Swap(n int) error {
+	if err := st.require(n + 1); err != nil {
+		return err
+	}
+	top := len(st.data) - 1
+	st.data[top], st.data[top-n] = st.data[top-n], st.data[top]
+	return nil
+}
+
+// require returns ErrStackUnderflow unless the stack holds at least n items.
+func (st *Stack) require(n int) error {
+	if len(st.data) < n {
+		return ErrStackUnderflow
+	}
+	return nil
+}
+
+// The unchecked accessors below are for instructions, whose stack
+// requirements the interpreter validates before they run.
+
+func (st *Stack) push(w *Word) {
+	st.data = append(st.data, *w)
+}
+
+func (st *Stack) dup(n int) {
+	st.data = append(st.data, st.data[len(st.data)-n])
+}
+
+func (st *Stack) swap(n int) {
+	top := len(st.data) - 1
+	st.data[top], st.data[top-n] = st.data[top-n], st.data[top]
+}
+
+func (st *Stack) pop() Word {
+	w := st.data[len(st.data)-1]
+	st.data = st.data[:len(st.data)-1]
+	return w
+}
+
+func (st *Stack) peek() *Word {
+	return &st.data[len(st.data)-1]
+}
+
+// back returns the item n places below the top; back(0) is the top.
+func (st *Stack) back(n int) *Word {
+	return &st.data[len(st.data)-1-n]
+}
+
+// topFirst returns a copy of the stack contents with the top item first, the
+// order used by Result and evm.json.
+func (st *Stack) topFirst() []Word {
+	out := make([]Word, len(st.data))
+	for i := range st.data {
+		out[i] = st.data[len(st.data)-1-i]
+	}
+	return out
+}
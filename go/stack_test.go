@@ -0,0 +1,74 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+)
+
+// stackOf returns a stack holding n items, 1 at the bottom up to n at the top.
+func stackOf(n int) *Stack {
+	st := newStack()
+	for i := 1; i <= n; i++ {
+		st.push(NewWord(uint64(i)))
+	}
+	return st
+}
+
+func TestStack(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		op      func(st *Stack) error
+		want    []uint64 // bottom first, checked on success
+		wantErr error
+	}{
+		{"Push", 2, func(st *Stack) error { return st.Push(NewWord(9)) }, []uint64{1, 2, 9}, nil},
+		{"Push/full", stackLimit, func(st *Stack) error { return st.Push(NewWord(9)) }, nil, ErrStackOverflow},
+		{"Pop", 2, func(st *Stack) error {
+			w, err := st.Pop()
+			if err == nil && w != *NewWord(2) {
+				t.Errorf("Pop() = %v; want 2", &w)
+			}
+			return err
+		}, []uint64{1}, nil},
+		{"Pop/empty", 0, func(st *Stack) error { _, err := st.Pop(); return err }, nil, ErrStackUnderflow},
+		{"Peek", 2, func(st *Stack) error {
+			w, err := st.Peek()
+			if err == nil {
+				w.SetUint64(7)
+			}
+			return err
+		}, []uint64{1, 7}, nil},
+		{"Peek/empty", 0, func(st *Stack) error { _, err := st.Peek(); return err }, nil, ErrStackUnderflow},
+		{"Dup1", 3, func(st *Stack) error { return st.Dup(1) }, []uint64{1, 2, 3, 3}, nil},
+		{"Dup3", 3, func(st *Stack) error { return st.Dup(3) }, []uint64{1, 2, 3, 1}, nil},
+		{"Dup/short", 2, func(st *Stack) error { return st.Dup(3) }, nil, ErrStackUnderflow},
+		{"Dup/full", stackLimit, func(st *Stack) error { return st.Dup(1) }, nil, ErrStackOverflow},
+		{"Swap1", 3, func(st *Stack) error { return st.Swap(1) }, []uint64{1, 3, 2}, nil},
+		{"Swap2", 3, func(st *Stack) error { return st.Swap(2) }, []uint64{3, 2, 1}, nil},
+		{"Swap/short", 2, func(st *Stack) error { return st.Swap(2) }, nil, ErrStackUnderflow},
+	}
+	for _, tt := range tests {
+		st := stackOf(tt.size)
+		err := tt.op(st)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v; want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			if st.Len() != tt.size {
+				t.Errorf("%s: failed op changed Len from %d to %d", tt.name, tt.size, st.Len())
+			}
+			continue
+		}
+		if st.Len() != len(tt.want) {
+			t.Errorf("%s: Len() = %d; want %d", tt.name, st.Len(), len(tt.want))
+			continue
+		}
+		for i, w := range tt.want {
+			if got := st.Data()[i]; got != *NewWord(w) {
+				t.Errorf("%s: item %d = %v; want %d", tt.name, i, &got, w)
+			}
+		}
+	}
+}
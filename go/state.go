@@ -0,0 +1,392 @@
+package evm
+
+// A StateDB is the world state the interpreter reads and writes: the balance,
+// nonce, code and storage of every account, plus the transaction-scoped
+// bookkeeping the gas rules depend on.
+type StateDB interface {
+	CreateAccount(addr Address)
+
+	GetBalance(addr Address) Word
+	SetBalance(addr Address, amount *Word)
+
+	GetNonce(addr Address) uint64
+	SetNonce(addr Address, nonce uint64)
+
+	GetCode(addr Address) []byte
+	SetCode(addr Address, code []byte)
+	// GetCodeHash returns the Keccak-256 hash of the code at addr, or zero
+	// if addr does not exist.
+	GetCodeHash(addr Address) Word
+
+	GetState(addr Address, key *Word) Word
+	SetState(addr Address, key, value *Word)
+	// GetCommittedState returns the value a storage slot held at the start
+	// of the transaction.
+	GetCommittedState(addr Address, key *Word) Word
+
+	// Transient storage (EIP-1153) is storage that is discarded at the end
+	// of every transaction.
+	GetTransientState(addr Address, key *Word) Word
+	SetTransientState(addr Address, key, value *Word)
+
+	// The refund counter accumulates gas to be given back at the end of the
+	// transaction, for instance for clearing storage.
+	AddRefund(gas uint64)
+	SubRefund(gas uint64)
+	GetRefund() uint64
+
+	// Suicide marks addr for deletion at the end of the transaction and
+	// clears its balance. It reports whether addr existed.
+	Suicide(addr Address) bool
+	HasSuicided(addr Address) bool
+	// CreatedInTx reports whether addr was created by CreateAccount in the
+	// current transaction.
+	CreatedInTx(addr Address) bool
+
+	// Exist reports whether addr is present in the state, even if empty.
+	Exist(addr Address) bool
+	// Empty reports whether addr is empty as defined by EIP-161: no code,
+	// a zero nonce and a zero balance. Accounts that do not exist are empty.
+	Empty(addr Address) bool
+
+	// The access list of EIP-2929 records the accounts and storage slots
+	// touched so far in the transaction, which are cheaper to access again.
+	AddressInAccessList(addr Address) bool
+	SlotInAccessList(addr Address, slot *Word) (addressOk, slotOk bool)
+	AddAddressToAccessList(addr Address)
+	AddSlotToAccessList(addr Address, slot *Word)
+
+	// AddLog records a log emitted in the transaction, and Logs returns
+	// them in the order they were added.
+	AddLog(log *Log)
+	Logs() []*Log
+
+	// Snapshot returns an identifier for the current state, and
+	// RevertToSnapshot undoes every modification made since the snapshot
+	// with that identifier was taken. Snapshots are reverted newest first.
+	Snapshot() int
+	RevertToSnapshot(id int)
+
+	// Prepare resets the transaction-scoped state, including the journal
+	// behind Snapshot, before a new transaction.
+	Prepare()
+	// Finalise deletes the accounts that have suicided. It is called at the
+	// end of a transaction.
+	Finalise()
+}
+
+// A MemoryStateDB is a StateDB held entirely in memory. The zero value is not
+// usable; create one with NewMemoryStateDB.
+type MemoryStateDB struct {
+	accounts   map[Address]*account
+	accessList map[Address]map[Word]struct{}
+	transient  map[Address]map[Word]Word
+	refund     uint64
+	logs       []*Log
+	journal    journal
+}
+
+type account struct {
+	balance  Word
+	nonce    uint64
+	code     []byte
+	codeHash Word // cached hash of code, zero until first needed
+	storage  map[Word]Word
+	// origin holds the value at the start of the transaction of every slot
+	// written since. Slots not in it have not changed.
+	origin   map[Word]Word
+	created  bool // by CreateAccount in the current transaction
+	suicided bool
+}
+
+func newAccount() *account {
+	return &account{storage: make(map[Word]Word), origin: make(map[Word]Word)}
+}
+
+// NewMemoryStateDB returns an empty MemoryStateDB.
+func NewMemoryStateDB() *MemoryStateDB {
+	return &MemoryStateDB{
+		accounts:   make(map[Address]*account),
+		accessList: make(map[Address]map[Word]struct{}),
+		transient:  make(map[Address]map[Word]Word),
+	}
+}
+
+// getAccount returns the account at addr, or nil if it does not exist.
+func (s *MemoryStateDB) getAccount(addr Address) *account {
+	return s.accounts[addr]
+}
+
+// getOrNewAccount returns the account at addr, creating it if needed.
+func (s *MemoryStateDB) getOrNewAccount(addr Address) *account {
+	if acc := s.accounts[addr]; acc != nil {
+		return acc
+	}
+	acc := newAccount()
+	s.accounts[addr] = acc
+	s.journal = append(s.journal, createAccountChange{addr: addr})
+	return acc
+}
+
+// CreateAccount creates an empty account at addr. An existing account is
+// replaced, but keeps its balance.
+func (s *MemoryStateDB) CreateAccount(addr Address) {
+	prev := s.getAccount(addr)
+	acc := newAccount()
+	acc.created = true
+	if prev != nil {
+		acc.balance = prev.balance
+	}
+	s.accounts[addr] = acc
+	s.journal = append(s.journal, createAccountChange{addr: addr, prev: prev})
+}
+
+func (s *MemoryStateDB) GetBalance(addr Address) Word {
+	if acc := s.getAccount(addr); acc != nil {
+		return acc.balance
+	}
+	return Word{}
+}
+
+func (s *MemoryStateDB) SetBalance(addr Address, amount *Word) {
+	acc := s.getOrNewAccount(addr)
+	s.journal = append(s.journal, balanceChange{addr: addr, prev: acc.balance})
+	acc.balance = *amount
+}
+
+func (s *MemoryStateDB) GetNonce(addr Address) uint64 {
+	if acc := s.getAccount(addr); acc != nil {
+		return acc.nonce
+	}
+	return 0
+}
+
+func (s *MemoryStateDB) SetNonce(addr Address, nonce uint64) {
+	acc := s.getOrNewAccount(addr)
+	s.journal = append(s.journal, nonceChange{addr: addr, prev: acc.nonce})
+	acc.nonce = nonce
+}
+
+// GetCode returns the code at addr. The slice must not be modified.
+func (s *MemoryStateDB) GetCode(addr Address) []byte {
+	if acc := s.getAccount(addr); acc != nil {
+		return acc.code
+	}
+	return nil
+}
+
+func (s *MemoryStateDB) GetCodeHash(addr Address) Word {
+	acc := s.getAccount(addr)
+	if acc == nil {
+		return Word{}
+	}
+	if acc.codeHash.IsZero() {
+		hash := Keccak256(acc.code)
+		acc.codeHash.SetBytes(hash[:])
+	}
+	return acc.codeHash
+}
+
+func (s *MemoryStateDB) SetCode(addr Address, code []byte) {
+	acc := s.getOrNewAccount(addr)
+	s.journal = append(s.journal, codeChange{addr: addr, prev: acc.code, prevHash: acc.codeHash})
+	acc.code = code
+	acc.codeHash = Word{}
+}
+
+func (s *MemoryStateDB) GetState(addr Address, key *Word) Word {
+	if acc := s.getAccount(addr); acc != nil {
+		return acc.storage[*key]
+	}
+	return Word{}
+}
+
+func (s *MemoryStateDB) SetState(addr Address, key, value *Word) {
+	acc := s.getOrNewAccount(addr)
+	prev := acc.storage[*key]
+	if _, ok := acc.origin[*key]; !ok {
+		acc.origin[*key] = prev
+	}
+	s.journal = append(s.journal, storageChange{addr: addr, key: *key, prev: prev})
+	acc.setState(key, value)
+}
+
+func (s *MemoryStateDB) GetCommittedState(addr Address, key *Word) Word {
+	acc := s.getAccount(addr)
+	if acc == nil {
+		return Word{}
+	}
+	if v, ok := acc.origin[*key]; ok {
+		return v
+	}
+	return acc.storage[*key]
+}
+
+// Storage returns a copy of the non-zero storage slots of addr, for
+// inspecting the state after execution.
+func (s *MemoryStateDB) Storage(addr Address) map[Word]Word {
+	acc := s.getAccount(addr)
+	if acc == nil {
+		return nil
+	}
+	dump := make(map[Word]Word, len(acc.storage))
+	for k, v := range acc.storage {
+		dump[k] = v
+	}
+	return dump
+}
+
+func (s *MemoryStateDB) GetTransientState(addr Address, key *Word) Word {
+	return s.transient[addr][*key]
+}
+
+func (s *MemoryStateDB) SetTransientState(addr Address, key, value *Word) {
+	prev := s.GetTransientState(addr, key)
+	s.journal = append(s.journal, transientStorageChange{addr: addr, key: *key, prev: prev})
+	s.setTransientState(addr, key, value)
+}
+
+func (s *MemoryStateDB) setTransientState(addr Address, key, value *Word) {
+	if value.IsZero() {
+		delete(s.transient[addr], *key)
+		return
+	}
+	if s.transient[addr] == nil {
+		s.transient[addr] = make(map[Word]Word)
+	}
+	s.transient[addr][*key] = *value
+}
+
+func (acc *account) setState(key, value *Word) {
+	if value.IsZero() {
+		delete(acc.storage, *key)
+	} else {
+		acc.storage[*key] = *value
+	}
+}
+
+func (s *MemoryStateDB) AddRefund(gas uint64) {
+	s.journal = append(s.journal, refundChange{prev: s.refund})
+	s.refund += gas
+}
+
+// SubRefund removes gas from the refund counter. It panics if the counter
+// would go below zero, which the gas rules never allow.
+func (s *MemoryStateDB) SubRefund(gas uint64) {
+	if gas > s.refund {
+		panic("evm: refund counter below zero")
+	}
+	s.journal = append(s.journal, refundChange{prev: s.refund})
+	s.refund -= gas
+}
+
+func (s *MemoryStateDB) GetRefund() uint64 {
+	return s.refund
+}
+
+func (s *MemoryStateDB) AddLog(log *Log) {
+	s.journal = append(s.journal, addLogChange{})
+	s.logs = append(s.logs, log)
+}
+
+func (s *MemoryStateDB) Logs() []*Log {
+	return s.logs
+}
+
+func (s *MemoryStateDB) Suicide(addr Address) bool {
+	acc := s.getAccount(addr)
+	if acc == nil {
+		return false
+	}
+	s.journal = append(s.journal, suicideChange{addr: addr, prev: acc.suicided, prevBalance: acc.balance})
+	acc.suicided = true
+	acc.balance.Clear()
+	return true
+}
+
+func (s *MemoryStateDB) HasSuicided(addr Address) bool {
+	if acc := s.getAccount(addr); acc != nil {
+		return acc.suicided
+	}
+	return false
+}
+
+func (s *MemoryStateDB) CreatedInTx(addr Address) bool {
+	if acc := s.getAccount(addr); acc != nil {
+		return acc.created
+	}
+	return false
+}
+
+func (s *MemoryStateDB) Exist(addr Address) bool {
+	return s.getAccount(addr) != nil
+}
+
+func (s *MemoryStateDB) Empty(addr Address) bool {
+	acc := s.getAccount(addr)
+	return acc == nil || (acc.nonce == 0 && acc.balance.IsZero() && len(acc.code) == 0)
+}
+
+func (s *MemoryStateDB) AddressInAccessList(addr Address) bool {
+	_, ok := s.accessList[addr]
+	return ok
+}
+
+func (s *MemoryStateDB) SlotInAccessList(addr Address, slot *Word) (addressOk, slotOk bool) {
+	slots, addressOk := s.accessList[addr]
+	if !addressOk {
+		return false, false
+	}
+	_, slotOk = slots[*slot]
+	return addressOk, slotOk
+}
+
+func (s *MemoryStateDB) AddAddressToAccessList(addr Address) {
+	if _, ok := s.accessList[addr]; !ok {
+		s.accessList[addr] = make(map[Word]struct{})
+		s.journal = append(s.journal, accessListAddAccountChange{addr: addr})
+	}
+}
+
+func (s *MemoryStateDB) AddSlotToAccessList(addr Address, slot *Word) {
+	s.AddAddressToAccessList(addr)
+	if _, ok := s.accessList[addr][*slot]; !ok {
+		s.accessList[addr][*slot] = struct{}{}
+		s.journal = append(s.journal, accessListAddSlotChange{addr: addr, slot: *slot})
+	}
+}
+
+// Snapshot returns the current length of the journal.
+func (s *MemoryStateDB) Snapshot() int {
+	return len(s.journal)
+}
+
+func (s *MemoryStateDB) RevertToSnapshot(id int) {
+	for i := len(s.journal) - 1; i >= id; i-- {
+		s.journal[i].revert(s)
+	}
+	s.journal = s.journal[:id]
+}
+
+func (s *MemoryStateDB) Prepare() {
+	for _, acc := range s.accounts {
+		acc.origin = make(map[Word]Word)
+		acc.created = false
+	}
+	s.accessList = make(map[Address]map[Word]struct{})
+	s.transient = make(map[Address]map[Word]Word)
+	s.refund = 0
+	s.logs = nil
+	s.journal = nil
+}
+
+// Finalise deletes the accounts that have suicided. The deletions are
+// journaled, so a Finalise within a transaction can be reverted.
+func (s *MemoryStateDB) Finalise() {
+	for addr, acc := range s.accounts {
+		if acc.suicided {
+			s.journal = append(s.journal, deleteAccountChange{addr: addr, prev: acc})
+			delete(s.accounts, addr)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package evm
+
+import "testing"
+
+func TestMemoryStateDBRevertToSnapshot(t *testing.T) {
+	var (
+		a   = Address{0xaa}
+		b   = Address{0xbb}
+		key = NewWord(1)
+	)
+	s := NewMemoryStateDB()
+	s.SetBalance(a, NewWord(10))
+	s.SetState(a, key, NewWord(5))
+
+	snap := s.Snapshot()
+	s.SetBalance(a, NewWord(3))
+	s.SetNonce(a, 7)
+	s.SetCode(a, []byte{0x00})
+	s.SetState(a, key, NewWord(0))
+	s.CreateAccount(b)
+	s.AddSlotToAccessList(a, key)
+	s.AddLog(&Log{Address: a})
+	inner := s.Snapshot()
+	s.SetBalance(b, NewWord(1))
+	s.Suicide(a)
+	s.AddLog(&Log{Address: b})
+
+	s.RevertToSnapshot(inner)
+	if !s.Exist(b) || s.HasSuicided(a) {
+		t.Fatalf("after inner revert: Exist(b) = %t, HasSuicided(a) = %t; want true, false", s.Exist(b), s.HasSuicided(a))
+	}
+	if got := s.GetBalance(a); got != *NewWord(3) {
+		t.Errorf("after inner revert: balance = %v; want 3", &got)
+	}
+	if got := len(s.Logs()); got != 1 {
+		t.Errorf("after inner revert: %d logs; want 1", got)
+	}
+
+	s.RevertToSnapshot(snap)
+	if got := s.GetBalance(a); got != *NewWord(10) {
+		t.Errorf("balance = %v; want 10", &got)
+	}
+	if got := s.GetNonce(a); got != 0 {
+		t.Errorf("nonce = %d; want 0", got)
+	}
+	if got := s.GetCode(a); got != nil {
+		t.Errorf("code = %x; want none", got)
+	}
+	if got := s.GetState(a, key); got != *NewWord(5) {
+		t.Errorf("storage = %v; want 5", &got)
+	}
+	if s.Exist(b) {
+		t.Error("created account survived revert")
+	}
+	if got := len(s.Logs()); got != 0 {
+		t.Errorf("%d logs survived revert", got)
+	}
+	if _, ok := s.SlotInAccessList(a, key); ok || s.AddressInAccessList(a) {
+		t.Error("access list additions survived revert")
+	}
+}
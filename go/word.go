@@ -0,0 +1,603 @@
+package evm
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// A Word is a 256-bit EVM word stored as four little-endian uint64 limbs:
+// Word[0] holds the least significant 64 bits. The zero value is 0.
+//
+// Arithmetic methods follow the math/big convention: z.Op(x, y) sets z to
+// the result and returns z, and z may alias x or y. All operations wrap
+// modulo 2^256 and none of them allocate.
+type Word [4]uint64
+
+// NewWord returns a Word set to v.
+func NewWord(v uint64) *Word {
+	return new(Word).SetUint64(v)
+}
+
+// WordFromBig returns b modulo 2^256 as a Word. Negative values are encoded in
+// two's complement.
+func WordFromBig(b *big.Int) *Word {
+	return new(Word).SetFromBig(b)
+}
+
+// Clear sets z to 0.
+func (z *Word) Clear() *Word {
+	*z = Word{}
+	return z
+}
+
+// SetAllOne sets z to 2^256-1.
+func (z *Word) SetAllOne() *Word {
+	*z = Word{^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)}
+	return z
+}
+
+// Set sets z to x.
+func (z *Word) Set(x *Word) *Word {
+	*z = *x
+	return z
+}
+
+// SetUint64 sets z to v.
+func (z *Word) SetUint64(v uint64) *Word {
+	*z = Word{v}
+	return z
+}
+
+// SetBytes interprets b as a big-endian unsigned integer and sets z to it.
+// Only the last 32 bytes of b are used.
+func (z *Word) SetBytes(b []byte) *Word {
+	if len(b) > 32 {
+		b = b[len(b)-32:]
+	}
+	z.Clear()
+	for i, n := 0, len(b); i < n; i++ {
+		shift := uint(i%8) * 8
+		z[i/8] |= uint64(b[n-1-i]) << shift
+	}
+	return z
+}
+
+// SetFromBig sets z to b modulo 2^256, encoding negative values in two's
+// complement.
+func (z *Word) SetFromBig(b *big.Int) *Word {
+	z.Clear()
+	words := b.Bits()
+	if bits.UintSize == 64 {
+		for i := 0; i < len(words) && i < 4; i++ {
+			z[i] = uint64(words[i])
+		}
+	} else {
+		for i := 0; i < len(words) && i < 8; i++ {
+			z[i/2] |= uint64(words[i]) << (32 * uint(i%2))
+		}
+	}
+	if b.Sign() < 0 {
+		z.Neg(z)
+	}
+	return z
+}
+
+// Bytes32 returns z as a 32-byte big-endian array.
+func (z *Word) Bytes32() [32]byte {
+	var b [32]byte
+	z.PutBytes32(b[:])
+	return b
+}
+
+// Bytes20 returns the low 20 bytes of z in big-endian order, the form in which
+// the EVM reads an address from a stack word.
+func (z *Word) Bytes20() [20]byte {
+	b := z.Bytes32()
+	var a [20]byte
+	copy(a[:], b[12:])
+	return a
+}
+
+// PutBytes32 writes z into dst[:32] in big-endian order.
+func (z *Word) PutBytes32(dst []byte) {
+	for i := 0; i < 32; i++ {
+		dst[31-i] = byte(z[i/8] >> (uint(i%8) * 8))
+	}
+}
+
+// Bytes returns the minimal big-endian encoding of z; 0 encodes as an empty
+// slice.
+func (z *Word) Bytes() []byte {
+	b := z.Bytes32()
+	return b[32-(z.BitLen()+7)/8:]
+}
+
+// ToBig returns z as a non-negative *big.Int.
+func (z *Word) ToBig() *big.Int {
+	b := z.Bytes32()
+	return new(big.Int).SetBytes(b[:])
+}
+
+// Hex returns z in 0x-prefixed hexadecimal without leading zeros.
+func (z *Word) Hex() string {
+	return "0x" + z.ToBig().Text(16)
+}
+
+// String implements fmt.Stringer.
+func (z *Word) String() string {
+	return z.Hex()
+}
+
+// IsZero reports whether z is 0.
+func (z *Word) IsZero() bool {
+	return z[0]|z[1]|z[2]|z[3] == 0
+}
+
+// IsUint64 reports whether z fits in a uint64.
+func (z *Word) IsUint64() bool {
+	return z[1]|z[2]|z[3] == 0
+}
+
+// Uint64 returns the low 64 bits of z.
+func (z *Word) Uint64() uint64 {
+	return z[0]
+}
+
+// BitLen returns the number of bits needed to represent z.
+func (z *Word) BitLen() int {
+	for i := 3; i >= 0; i-- {
+		if z[i] != 0 {
+			return i*64 + bits.Len64(z[i])
+		}
+	}
+	return 0
+}
+
+// isNegative reports whether z is negative when read as a two's complement
+// signed integer.
+func (z *Word) isNegative() bool {
+	return z[3]>>63 == 1
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of z read as a two's
+// complement signed integer.
+func (z *Word) Sign() int {
+	switch {
+	case z.IsZero():
+		return 0
+	case z.isNegative():
+		return -1
+	}
+	return 1
+}
+
+// Cmp compares z and x as unsigned integers and returns -1, 0 or +1.
+func (z *Word) Cmp(x *Word) int {
+	for i := 3; i >= 0; i-- {
+		switch {
+		case z[i] < x[i]:
+			return -1
+		case z[i] > x[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Eq reports whether z == x.
+func (z *Word) Eq(x *Word) bool {
+	return *z == *x
+}
+
+// Lt reports whether z < x as unsigned integers.
+func (z *Word) Lt(x *Word) bool {
+	return z.Cmp(x) < 0
+}
+
+// Gt reports whether z > x as unsigned integers.
+func (z *Word) Gt(x *Word) bool {
+	return z.Cmp(x) > 0
+}
+
+// Slt reports whether z < x as signed integers.
+func (z *Word) Slt(x *Word) bool {
+	zNeg, xNeg := z.isNegative(), x.isNegative()
+	if zNeg != xNeg {
+		return zNeg
+	}
+	return z.Lt(x)
+}
+
+// Sgt reports whether z > x as signed integers.
+func (z *Word) Sgt(x *Word) bool {
+	return x.Slt(z)
+}
+
+// Add sets z to x + y.
+func (z *Word) Add(x, y *Word) *Word {
+	var c uint64
+	z[0], c = bits.Add64(x[0], y[0], 0)
+	z[1], c = bits.Add64(x[1], y[1], c)
+	z[2], c = bits.Add64(x[2], y[2], c)
+	z[3], _ = bits.Add64(x[3], y[3], c)
+	return z
+}
+
+// addCarry sets z to x + y and returns the carry out of bit 255.
+func (z *Word) addCarry(x, y *Word) uint64 {
+	var c uint64
+	z[0], c = bits.Add64(x[0], y[0], 0)
+	z[1], c = bits.Add64(x[1], y[1], c)
+	z[2], c = bits.Add64(x[2], y[2], c)
+	z[3], c = bits.Add64(x[3], y[3], c)
+	return c
+}
+
+// Sub sets z to x - y.
+func (z *Word) Sub(x, y *Word) *Word {
+	var b uint64
+	z[0], b = bits.Sub64(x[0], y[0], 0)
+	z[1], b = bits.Sub64(x[1], y[1], b)
+	z[2], b = bits.Sub64(x[2], y[2], b)
+	z[3], _ = bits.Sub64(x[3], y[3], b)
+	return z
+}
+
+// Neg sets z to -x.
+func (z *Word) Neg(x *Word) *Word {
+	return z.Sub(&Word{}, x)
+}
+
+// abs sets z to the absolute value of x read as a signed integer.
+func (z *Word) abs(x *Word) *Word {
+	if x.isNegative() {
+		return z.Neg(x)
+	}
+	return z.Set(x)
+}
+
+// Mul sets z to x * y.
+func (z *Word) Mul(x, y *Word) *Word {
+	var res Word
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; i+j < 4; j++ {
+			hi, lo := bits.Mul64(x[i], y[j])
+			var c uint64
+			lo, c = bits.Add64(lo, res[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			res[i+j] = lo
+			carry = hi
+		}
+	}
+	*z = res
+	return z
+}
+
+// umul returns the full 512-bit product of x and y.
+func umul(x, y *Word) [8]uint64 {
+	var res [8]uint64
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(x[i], y[j])
+			var c uint64
+			lo, c = bits.Add64(lo, res[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			res[i+j] = lo
+			carry = hi
+		}
+		res[i+4] = carry
+	}
+	return res
+}
+
+// Div sets z to x / y, or 0 if y is 0.
+func (z *Word) Div(x, y *Word) *Word {
+	if y.IsZero() || x.Lt(y) {
+		return z.Clear()
+	}
+	if x.IsUint64() {
+		return z.SetUint64(x[0] / y[0])
+	}
+	var quot [4]uint64
+	udivrem(quot[:], x[:], y)
+	*z = quot
+	return z
+}
+
+// Mod sets z to x % y, or 0 if y is 0.
+func (z *Word) Mod(x, y *Word) *Word {
+	if y.IsZero() {
+		return z.Clear()
+	}
+	if x.Lt(y) {
+		return z.Set(x)
+	}
+	if x.IsUint64() {
+		return z.SetUint64(x[0] % y[0])
+	}
+	var quot [4]uint64
+	*z = udivrem(quot[:], x[:], y)
+	return z
+}
+
+// SDiv sets z to x / y with both operands read as signed integers, rounding
+// toward zero. Division by 0 yields 0.
+func (z *Word) SDiv(x, y *Word) *Word {
+	if y.IsZero() {
+		return z.Clear()
+	}
+	neg := x.isNegative() != y.isNegative()
+	var a, b Word
+	z.Div(a.abs(x), b.abs(y))
+	if neg {
+		z.Neg(z)
+	}
+	return z
+}
+
+// SMod sets z to x % y with both operands read as signed integers; the result
+// takes the sign of x. Modulo 0 yields 0.
+func (z *Word) SMod(x, y *Word) *Word {
+	if y.IsZero() {
+		return z.Clear()
+	}
+	neg := x.isNegative()
+	var a, b Word
+	z.Mod(a.abs(x), b.abs(y))
+	if neg {
+		z.Neg(z)
+	}
+	return z
+}
+
+// AddMod sets z to (x + y) % m computed without intermediate overflow, or 0 if
+// m is 0.
+func (z *Word) AddMod(x, y, m *Word) *Word {
+	if m.IsZero() {
+		return z.Clear()
+	}
+	var sum Word
+	if carry := sum.addCarry(x, y); carry != 0 {
+		u := [5]uint64{sum[0], sum[1], sum[2], sum[3], carry}
+		var quot [5]uint64
+		*z = udivrem(quot[:], u[:], m)
+		return z
+	}
+	return z.Mod(&sum, m)
+}
+
+// MulMod sets z to (x * y) % m computed without intermediate overflow, or 0 if
+// m is 0.
+func (z *Word) MulMod(x, y, m *Word) *Word {
+	if m.IsZero() {
+		return z.Clear()
+	}
+	p := umul(x, y)
+	n := len(p)
+	for n > 0 && p[n-1] == 0 {
+		n--
+	}
+	if n <= 4 {
+		var lo Word
+		copy(lo[:], p[:4])
+		return z.Mod(&lo, m)
+	}
+	var quot [8]uint64
+	*z = udivrem(quot[:], p[:n], m)
+	return z
+}
+
+// Exp sets z to base**exponent.
+func (z *Word) Exp(base, exponent *Word) *Word {
+	res := Word{1}
+	b := *base
+	for i, n := 0, exponent.BitLen(); i < n; i++ {
+		if exponent[i/64]>>(uint(i)%64)&1 == 1 {
+			res.Mul(&res, &b)
+		}
+		b.Mul(&b, &b)
+	}
+	*z = res
+	return z
+}
+
+// ExtendSign sets z to x sign-extended from its byte at index back, counting
+// from the least significant byte. If back is 31 or more, z is set to x.
+func (z *Word) ExtendSign(x, back *Word) *Word {
+	if !back.IsUint64() || back[0] >= 31 {
+		return z.Set(x)
+	}
+	bit := uint(back[0]*8 + 7)
+	limb, off := bit/64, bit%64
+	mask := uint64(1)<<off - 1
+	*z = *x
+	if x[limb]>>off&1 == 1 {
+		z[limb] |= ^mask
+		for i := limb + 1; i < 4; i++ {
+			z[i] = ^uint64(0)
+		}
+	} else {
+		z[limb] &= mask | uint64(1)<<off
+		for i := limb + 1; i < 4; i++ {
+			z[i] = 0
+		}
+	}
+	return z
+}
+
+// Lsh sets z to x << n.
+func (z *Word) Lsh(x *Word, n uint) *Word {
+	if n >= 256 {
+		return z.Clear()
+	}
+	limbs, off := n/64, n%64
+	var res Word
+	for i := 3; i >= int(limbs); i-- {
+		res[i] = x[i-int(limbs)] << off
+		if off != 0 && i-int(limbs)-1 >= 0 {
+			res[i] |= x[i-int(limbs)-1] >> (64 - off)
+		}
+	}
+	*z = res
+	return z
+}
+
+// Rsh sets z to x >> n, filling with zeros.
+func (z *Word) Rsh(x *Word, n uint) *Word {
+	if n >= 256 {
+		return z.Clear()
+	}
+	limbs, off := n/64, n%64
+	var res Word
+	for i := 0; i+int(limbs) < 4; i++ {
+		res[i] = x[i+int(limbs)] >> off
+		if off != 0 && i+int(limbs)+1 < 4 {
+			res[i] |= x[i+int(limbs)+1] << (64 - off)
+		}
+	}
+	*z = res
+	return z
+}
+
+// SRsh sets z to x >> n, filling with copies of the sign bit.
+func (z *Word) SRsh(x *Word, n uint) *Word {
+	if !x.isNegative() {
+		return z.Rsh(x, n)
+	}
+	if n >= 256 {
+		return z.SetAllOne()
+	}
+	var fill Word
+	fill.SetAllOne().Lsh(&fill, 256-n)
+	z.Rsh(x, n)
+	return z.Or(z, &fill)
+}
+
+// Byte sets z to the byte of x at index n, counting from the most significant
+// byte, or 0 if n is 32 or more.
+func (z *Word) Byte(x, n *Word) *Word {
+	if !n.IsUint64() || n[0] >= 32 {
+		return z.Clear()
+	}
+	shift := (31 - uint(n[0])) * 8
+	return z.SetUint64(x[shift/64] >> (shift % 64) & 0xff)
+}
+
+// And sets z to x & y.
+func (z *Word) And(x, y *Word) *Word {
+	z[0], z[1], z[2], z[3] = x[0]&y[0], x[1]&y[1], x[2]&y[2], x[3]&y[3]
+	return z
+}
+
+// Or sets z to x | y.
+func (z *Word) Or(x, y *Word) *Word {
+	z[0], z[1], z[2], z[3] = x[0]|y[0], x[1]|y[1], x[2]|y[2], x[3]|y[3]
+	return z
+}
+
+// Xor sets z to x ^ y.
+func (z *Word) Xor(x, y *Word) *Word {
+	z[0], z[1], z[2], z[3] = x[0]^y[0], x[1]^y[1], x[2]^y[2], x[3]^y[3]
+	return z
+}
+
+// Not sets z to ^x.
+func (z *Word) Not(x *Word) *Word {
+	z[0], z[1], z[2], z[3] = ^x[0], ^x[1], ^x[2], ^x[3]
+	return z
+}
+
+// udivrem divides u by d using Knuth's Algorithm D (TAOCP vol. 2, 4.3.1),
+// writing the quotient into quot and returning the remainder. quot must have
+// room for len(u) limbs and d must be non-zero.
+func udivrem(quot, u []uint64, d *Word) Word {
+	n := 4
+	for d[n-1] == 0 {
+		n--
+	}
+
+	var rem Word
+	if n == 1 {
+		var r uint64
+		for i := len(u) - 1; i >= 0; i-- {
+			quot[i], r = bits.Div64(r, u[i], d[0])
+		}
+		rem[0] = r
+		return rem
+	}
+
+	// Normalize so that the top bit of the divisor is set.
+	s := uint(bits.LeadingZeros64(d[n-1]))
+	var dn [4]uint64
+	for i := n - 1; i > 0; i-- {
+		dn[i] = d[i]<<s | d[i-1]>>(64-s)
+	}
+	dn[0] = d[0] << s
+
+	var unBuf [9]uint64
+	un := unBuf[:len(u)+1]
+	un[len(u)] = u[len(u)-1] >> (64 - s)
+	for i := len(u) - 1; i > 0; i-- {
+		un[i] = u[i]<<s | u[i-1]>>(64-s)
+	}
+	un[0] = u[0] << s
+
+	for j := len(u) - n; j >= 0; j-- {
+		// Estimate the quotient digit from the top two limbs.
+		var qhat, rhat uint64
+		overflow := false
+		if un[j+n] >= dn[n-1] {
+			qhat = ^uint64(0)
+			var c uint64
+			rhat, c = bits.Add64(un[j+n-1], dn[n-1], 0)
+			overflow = c != 0
+		} else {
+			qhat, rhat = bits.Div64(un[j+n], un[j+n-1], dn[n-1])
+		}
+		for !overflow {
+			hi, lo := bits.Mul64(qhat, dn[n-2])
+			if hi < rhat || (hi == rhat && lo <= un[j+n-2]) {
+				break
+			}
+			qhat--
+			var c uint64
+			rhat, c = bits.Add64(rhat, dn[n-1], 0)
+			overflow = c != 0
+		}
+
+		// Multiply and subtract.
+		var borrow, carry uint64
+		for i := 0; i < n; i++ {
+			hi, lo := bits.Mul64(qhat, dn[i])
+			var c uint64
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			un[i+j], borrow = bits.Sub64(un[i+j], lo, borrow)
+			carry = hi
+		}
+		un[j+n], borrow = bits.Sub64(un[j+n], carry, borrow)
+
+		// The estimate was one too large; add the divisor back.
+		if borrow != 0 {
+			qhat--
+			var c uint64
+			for i := 0; i < n; i++ {
+				un[i+j], c = bits.Add64(un[i+j], dn[i], c)
+			}
+			un[j+n] += c
+		}
+		quot[j] = qhat
+	}
+
+	// Unnormalize the remainder.
+	for i := 0; i < n; i++ {
+		rem[i] = un[i]>>s | un[i+1]<<(64-s)
+	}
+	return rem
+}
@@ -0,0 +1,141 @@
+package evm
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+var (
+	two256 = new(big.Int).Lsh(big.NewInt(1), 256)
+	two255 = new(big.Int).Lsh(big.NewInt(1), 255)
+)
+
+// randWord returns a random Word biased towards the edge cases that matter for
+// limb arithmetic: zero limbs, all-one limbs and small values.
+func randWord(r *rand.Rand) Word {
+	var w Word
+	for i := range w {
+		switch r.Intn(4) {
+		case 0:
+			w[i] = 0
+		case 1:
+			w[i] = ^uint64(0)
+		default:
+			w[i] = r.Uint64()
+		}
+	}
+	if r.Intn(4) == 0 {
+		w[1], w[2], w[3] = 0, 0, 0
+	}
+	return w
+}
+
+// toSigned reads b, which must be in [0, 2^256), as a two's complement value.
+func toSigned(b *big.Int) *big.Int {
+	if b.Cmp(two255) >= 0 {
+		return new(big.Int).Sub(b, two256)
+	}
+	return b
+}
+
+func TestWordMatchesBig(t *testing.T) {
+	mod := func(b *big.Int) *big.Int {
+		return new(big.Int).Mod(b, two256)
+	}
+	binary := []struct {
+		name string
+		word func(z, x, y *Word) *Word
+		big  func(x, y *big.Int) *big.Int
+	}{
+		{"Add", (*Word).Add, func(x, y *big.Int) *big.Int { return mod(new(big.Int).Add(x, y)) }},
+		{"Sub", (*Word).Sub, func(x, y *big.Int) *big.Int { return mod(new(big.Int).Sub(x, y)) }},
+		{"Mul", (*Word).Mul, func(x, y *big.Int) *big.Int { return mod(new(big.Int).Mul(x, y)) }},
+		{"Div", (*Word).Div, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return new(big.Int).Div(x, y)
+		}},
+		{"Mod", (*Word).Mod, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return new(big.Int).Mod(x, y)
+		}},
+		{"SDiv", (*Word).SDiv, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return mod(new(big.Int).Quo(toSigned(x), toSigned(y)))
+		}},
+		{"SMod", (*Word).SMod, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return mod(new(big.Int).Rem(toSigned(x), toSigned(y)))
+		}},
+		{"Exp", (*Word).Exp, func(x, y *big.Int) *big.Int { return new(big.Int).Exp(x, y, two256) }},
+	}
+	ternary := []struct {
+		name string
+		word func(z, x, y, m *Word) *Word
+		big  func(x, y *big.Int) *big.Int
+	}{
+		{"AddMod", (*Word).AddMod, new(big.Int).Add},
+		{"MulMod", (*Word).MulMod, new(big.Int).Mul},
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		x, y, m := randWord(r), randWord(r), randWord(r)
+		bx, by, bm := x.ToBig(), y.ToBig(), m.ToBig()
+
+		for _, op := range binary {
+			got := op.word(new(Word), &x, &y).ToBig()
+			if want := op.big(bx, by); got.Cmp(want) != 0 {
+				t.Errorf("%s(%#x, %#x) = %#x; want %#x", op.name, bx, by, got, want)
+			}
+		}
+		for _, op := range ternary {
+			got := op.word(new(Word), &x, &y, &m).ToBig()
+			want := new(big.Int)
+			if bm.Sign() != 0 {
+				want.Mod(op.big(bx, by), bm)
+			}
+			if got.Cmp(want) != 0 {
+				t.Errorf("%s(%#x, %#x, %#x) = %#x; want %#x", op.name, bx, by, bm, got, want)
+			}
+		}
+
+		n := uint(r.Intn(260))
+		if got, want := new(Word).Lsh(&x, n).ToBig(), mod(new(big.Int).Lsh(bx, n)); got.Cmp(want) != 0 {
+			t.Errorf("Lsh(%#x, %d) = %#x; want %#x", bx, n, got, want)
+		}
+		if got, want := new(Word).Rsh(&x, n).ToBig(), new(big.Int).Rsh(bx, n); got.Cmp(want) != 0 {
+			t.Errorf("Rsh(%#x, %d) = %#x; want %#x", bx, n, got, want)
+		}
+		if got, want := new(Word).SRsh(&x, n).ToBig(), mod(new(big.Int).Rsh(toSigned(bx), n)); got.Cmp(want) != 0 {
+			t.Errorf("SRsh(%#x, %d) = %#x; want %#x", bx, n, got, want)
+		}
+	}
+}
+
+func TestWordExtendSign(t *testing.T) {
+	tests := []struct {
+		x, back uint64
+		want    string
+	}{
+		{0x7f, 0, "0x7f"},
+		{0xff, 0, "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{0x12ff, 0, "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{0x127f, 0, "0x7f"},
+		{0x8000, 1, "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff8000"},
+		{0x8000, 31, "0x8000"},
+	}
+	for _, tt := range tests {
+		if got := new(Word).ExtendSign(NewWord(tt.x), NewWord(tt.back)).Hex(); got != tt.want {
+			t.Errorf("ExtendSign(%#x, %d) = %s; want %s", tt.x, tt.back, got, tt.want)
+		}
+	}
+}